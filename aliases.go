@@ -0,0 +1,54 @@
+package cmd
+
+import "strings"
+
+// Aliaser is implemented by commands that can also be called by other names, e.g. "rm" for
+// "remove". Aliases are listed in the command's help, and after the command's name in the program
+// usage with WithAliasesInUsage(true), e.g. "remove, rm". "help rm" shows the help of remove.
+type Aliaser interface {
+	Aliases() []string
+}
+
+// commandAliases returns the aliases of cmd if it has any
+func commandAliases(cmd Command) []string {
+	if a, ok := cmd.(Aliaser); ok {
+		return a.Aliases()
+	}
+	return nil
+}
+
+// hasName checks whether cmd is called name, either by its name or one of its aliases
+func hasName(cmd Command, name string) bool {
+	if cmd.Name() == name {
+		return true
+	}
+	for _, alias := range commandAliases(cmd) {
+		if alias == name {
+			return true
+		}
+	}
+	return false
+}
+
+// hasNameFold checks whether cmd is called name ignoring case, by its name or one of its aliases
+func hasNameFold(cmd Command, name string) bool {
+	if strings.EqualFold(cmd.Name(), name) {
+		return true
+	}
+	for _, alias := range commandAliases(cmd) {
+		if strings.EqualFold(alias, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// tableName returns the name cmd is listed under in the program usage, e.g. "remove, rm" when
+// aliases are listed in it
+func (p *Program) tableName(cmd Command) string {
+	aliases := commandAliases(cmd)
+	if !p.aliasUsage || len(aliases) == 0 {
+		return cmd.Name()
+	}
+	return cmd.Name() + ", " + strings.Join(aliases, ", ")
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import "fmt"
+
+// ArgsValidator is implemented by commands that check their args before being run, after flags
+// have been parsed. A failed check prints the command's usage and Run returns an ErrInvalidArgs,
+// so the command itself can assume its args are well formed. The ArgSpec helpers cover the
+// common cases:
+//
+//	func (c *greetCommand) ValidateArgs(args []string) error {
+//		return cmd.RangeArgs(0, 1)(args)
+//	}
+type ArgsValidator interface {
+	ValidateArgs(args []string) error
+}
+
+// ArgSpec checks the args given to a command.
+type ArgSpec func(args []string) error
+
+// ExactArgs accepts exactly n args.
+func ExactArgs(n int) ArgSpec {
+	return RangeArgs(n, n)
+}
+
+// MinimumArgs accepts n or more args.
+func MinimumArgs(n int) ArgSpec {
+	return RangeArgs(n, -1)
+}
+
+// RangeArgs accepts between min and max args inclusive, a negative max means no upper limit.
+func RangeArgs(min, max int) ArgSpec {
+	return func(args []string) error {
+		n := len(args)
+		switch {
+		case min == max && n != min:
+			return fmt.Errorf("accepts %s, received %d", pluralArgs(min), n)
+		case max < 0 && n < min:
+			return fmt.Errorf("requires at least %s, received %d", pluralArgs(min), n)
+		case max >= 0 && (n < min || n > max):
+			return fmt.Errorf("accepts between %d and %d args, received %d", min, max, n)
+		}
+		return nil
+	}
+}
+
+// pluralArgs returns "1 arg" or "n args"
+func pluralArgs(n int) string {
+	if n == 1 {
+		return "1 arg"
+	}
+	return fmt.Sprintf("%d args", n)
+}
+
+// validateArgs checks args with cmd's ValidateArgs, if it has one
+func (p *Program) validateArgs(cmd Command, args []string) error {
+	v, ok := cmd.(ArgsValidator)
+	if !ok {
+		return nil
+	}
+	if err := v.ValidateArgs(args); err != nil {
+		return &ErrInvalidArgs{commandName: p.commandPath(cmd), cause: err}
+	}
+	return nil
+}
+
+// ErrInvalidArgs is returned when a command's ValidateArgs rejects its args:
+//
+//	prog greet: accepts 1 arg, received 2
+type ErrInvalidArgs struct {
+	commandName string
+	cause       error
+}
+
+// Error implements the error interface
+func (e *ErrInvalidArgs) Error() string {
+	return fmt.Sprintf("%s: %v", e.commandName, e.cause)
+}
+
+// Unwrap returns the error ValidateArgs returned
+func (e *ErrInvalidArgs) Unwrap() error {
+	return e.cause
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"io"
+)
+
+// allCommands returns the program's commands followed by the builtin commands it provides. Builtins
+// are only provided to programs with commands of their own, and are left out when a command of the
+// same name exists.
+func (p *Program) allCommands() []Command {
+	if len(p.commands) == 0 {
+		return nil
+	}
+
+	cmds := p.commands
+	for _, b := range p.builtins {
+		shadowed := false
+		for _, cmd := range p.commands {
+			if hasName(cmd, b.Name()) {
+				shadowed = true
+				break
+			}
+		}
+		if !shadowed {
+			cmds = append(cmds[:len(cmds):len(cmds)], b)
+		}
+	}
+	return cmds
+}
+
+// isBuiltin checks whether cmd is one of the commands the program provides itself
+func isBuiltin(cmd Command) bool {
+	switch cmd.(type) {
+	case *commandsCommand, *shellCompletionCommand, *helpCommand, *versionCommand:
+		return true
+	}
+	return false
+}
+
+// ListCommands writes each command to w on its own line as its name and description separated by
+// a tab, or just its name when namesOnly is set. The output has no headings or styling so it can
+// be piped into tools like fzf, grep and awk. Hidden commands are left out, as in the usage.
+func (p *Program) ListCommands(w io.Writer, namesOnly bool) error {
+	return p.listCommands(w, namesOnly, false)
+}
+
+// listCommands lists the commands as for ListCommands, including hidden commands when all is set
+func (p *Program) listCommands(w io.Writer, namesOnly, all bool) error {
+	for _, cmd := range visibleCommands(p.allCommands(), all) {
+		var err error
+		if namesOnly {
+			_, err = fmt.Fprintln(w, cmd.Name())
+		} else {
+			_, err = fmt.Fprintf(w, "%s\t%s\n", cmd.Name(), cmd.Desc())
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// commandsCommand lists the program's commands for use in scripts
+type commandsCommand struct {
+	p         *Program
+	namesOnly bool
+	all       bool
+}
+
+const commandsHelp = `
+List the available commands, one per line with the name and description separated by a tab.
+`
+
+func (c *commandsCommand) Name() string { return "commands" }
+func (c *commandsCommand) Args() string { return "" }
+func (c *commandsCommand) Desc() string { return "List the available commands" }
+func (c *commandsCommand) Help() string { return commandsHelp }
+func (c *commandsCommand) Register(fs *flag.FlagSet) {
+	fs.BoolVar(&c.namesOnly, "names-only", false, "Only list the command names")
+	fs.BoolVar(&c.all, "all", false, "Include hidden commands")
+}
+
+func (c *commandsCommand) Run(ctx Context, args []string) error {
+	return c.p.listCommands(ctx.Stdout(), c.namesOnly, c.all)
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Cacher is implemented by expensive, deterministic commands whose output can be replayed instead
+// of running them again. CacheKey returns the key the output of a run with args is cached under,
+// and false when that run shouldn't be cached. Only what the command writes to its context's stdout
+// is cached.
+type Cacher interface {
+	CacheKey(args []string) (string, bool)
+}
+
+// WithResultCache caches the output of commands implementing Cacher in the program's cache dir for
+// ttl. While a cached result is fresh it is written out in place of running the command, still
+// inside the program's hooks and middleware and the command's PreRun and PostRun. Results are
+// cached apart for each set of flag values and output format. Giving the -no-cache flag runs the
+// command regardless, refreshing its cached result.
+func WithResultCache(ttl time.Duration) Option {
+	return func(p *Program) {
+		p.cacheTTL = ttl
+		p.flags.BoolVar(&p.noCache, "no-cache", false, "Run commands instead of replaying cached results")
+	}
+}
+
+// cachePath returns the file the output of cmd run with the flags and args of fs is cached in, or
+// false when it isn't cached
+func (p *Program) cachePath(fs *flag.FlagSet, cmd Command) (string, bool) {
+	c, ok := cmd.(Cacher)
+	if !ok || p.cacheTTL <= 0 {
+		return "", false
+	}
+
+	key, ok := c.CacheKey(fs.Args())
+	if !ok {
+		return "", false
+	}
+
+	dir, err := p.CacheDir()
+	if err != nil {
+		return "", false
+	}
+
+	h := sha256.New()
+	io.WriteString(h, p.commandPath(cmd)+"\x00"+key+"\x00"+OutputFormat(p.env.GetDefaultContext()))
+	fs.VisitAll(func(f *flag.Flag) {
+		if f.Name == "no-cache" && p.isGlobalFlag(f) {
+			return
+		}
+		io.WriteString(h, "\x00"+f.Name+"="+f.Value.String())
+	})
+	return filepath.Join(dir, "results", hex.EncodeToString(h.Sum(nil))), true
+}
+
+// caching wraps fn to replay the output cached in path while it is fresh, and otherwise to run fn
+// and cache what it writes to stdout when it succeeds
+func (p *Program) caching(path string, fn RunFunc) RunFunc {
+	return func(env *Environment, cmd Command, args []string) error {
+		if b, ok := p.readCache(path); ok && !p.noCache {
+			_, err := env.stdout.Write(b)
+			return err
+		}
+
+		save := env.cacheStdout(path)
+		err := fn(env, cmd, args)
+		save(err == nil)
+		return err
+	}
+}
+
+// readCache returns the cached output in path, when it is younger than the program's ttl
+func (p *Program) readCache(path string) ([]byte, bool) {
+	fi, err := os.Stat(path)
+	if err != nil || time.Since(fi.ModTime()) > p.cacheTTL {
+		return nil, false
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	return b, true
+}
+
+// cacheStdout copies what is written to stdout into a buffer until the returned func is called,
+// which puts the original stdout back and, when save is set, stores the copy in path
+func (e *Environment) cacheStdout(path string) func(save bool) {
+	prev := e.stdout
+	var buf bytes.Buffer
+	e.stdout = io.MultiWriter(prev, &buf)
+
+	return func(save bool) {
+		e.stdout = prev
+		if !save {
+			return
+		}
+		if err := replaceFile(path, buf.Bytes()); err != nil {
+			e.warn("unable to cache result: %v", err)
+		}
+	}
+}
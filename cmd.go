@@ -2,21 +2,74 @@ package cmd
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"log"
+	"log/slog"
 	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
-	"text/tabwriter"
+	"text/template"
+	"time"
 )
 
+// Out and Err log to the process's stdout and stderr.
+//
+// Deprecated: they are shared by every Program in the process. Use Program.Out and Program.Err, or
+// Context.OutLog and Context.ErrLog from a command, which write to the program's own stdout and
+// stderr.
 var Out = log.New(os.Stdout, "", 0)
 var Err = log.New(os.Stderr, "", 0)
 
+// Warn prints a warning to Err.
+func Warn(format string, v ...interface{}) {
+	Err.Printf("warning: "+format, v...)
+}
+
 type Context interface {
+	context.Context
+
 	WorkingDir() string
+	Stdin() io.Reader
+	Stdout() io.Writer
+
+	// Emit reports a progress event, such as an Event, to front-ends following the program's
+	// progress. It does nothing unless the program was given -progress=json.
+	Emit(event interface{})
+
+	// Run runs another of the program's commands with args, as if it had been given on the
+	// command line, e.g. to have deploy run build first.
+	Run(name string, args []string) error
+
+	// Flags returns the parsed flags of the running command, including the program's persistent
+	// flags.
+	Flags() *flag.FlagSet
+
+	// OutputFormat returns the output format chosen for the running command, as OutputFormat does.
+	OutputFormat() string
+
+	// Encode writes v to stdout in the chosen output format, as Encode does.
+	Encode(v interface{}) error
+
+	// OutLog and ErrLog return the program's loggers, Program.Out and Program.Err, writing to its
+	// stdout and stderr.
+	OutLog() *log.Logger
+	ErrLog() *log.Logger
+
+	// Environment returns a copy of the environment the command runs in, for reading its Env and
+	// Args. Changing the copy doesn't change the program's.
+	Environment() *Environment
+
+	// DryRun reports whether -dry-run was given, asking the command to show what it would do
+	// without doing it. It is always false unless the program was created with WithDryRun.
+	DryRun() bool
+
+	// Logger returns the program's structured logger for diagnostics, as set by WithLogger.
+	Logger() *slog.Logger
 }
 
 type Command interface {
@@ -32,18 +85,58 @@ type Environment struct {
 	WorkingDir     string
 	Args           []string
 	Env            []string
+	stdin          io.Reader
 	stdout, stderr io.Writer
+	ctx            context.Context                        // context for the running command
+	events         io.Writer                              // where progress events are written, nil when they're off
+	runSub         func(name string, args []string) error // runs another command for Context.Run
+	depth          int                                    // how many commands deep Context.Run has gone
+	dryRun         bool                                   // set by -dry-run
+	flags          *flag.FlagSet                          // parsed flags of the running command
+	outLog, errLog *log.Logger                            // loggers writing to stdout and stderr
+	logger         *slog.Logger                           // structured logger, set by WithLogger
 }
 
 func (e *Environment) GetStdio() (io.Writer, io.Writer) { return e.stdout, e.stderr }
+
+// warn prints a warning to the environment's stderr
+func (e *Environment) warn(format string, v ...interface{}) {
+	fmt.Fprintf(e.stderr, "warning: "+format+"\n", v...)
+}
+
+// SetStdin replaces the stdin commands read from, e.g. with a bytes.Buffer in tests.
+func (e *Environment) SetStdin(r io.Reader) { e.stdin = r }
 func (e *Environment) GetDefaultContext() Context {
 	return &defaultContext{
-		wd: e.WorkingDir,
+		Context: e.context(),
+		wd:      e.WorkingDir,
+		stdin:   e.stdin,
+		stdout:  e.stdout,
+		events:  e.events,
+		runSub:  e.runSub,
+		flags:   e.flags,
+		outLog:  e.outLog,
+		errLog:  e.errLog,
+		env:     e,
+		dryRun:  e.dryRun,
+		logger:  e.logger,
 	}
 }
 
 type defaultContext struct {
-	wd string // working directory
+	context.Context
+
+	wd     string // working directory
+	stdin  io.Reader
+	stdout io.Writer
+	events io.Writer
+	runSub func(name string, args []string) error
+	flags  *flag.FlagSet
+	outLog *log.Logger
+	errLog *log.Logger
+	env    *Environment
+	dryRun bool
+	logger *slog.Logger
 }
 
 var _ Context = (*defaultContext)(nil)
@@ -52,136 +145,510 @@ func (dc *defaultContext) WorkingDir() string {
 	return dc.wd
 }
 
+func (dc *defaultContext) Stdin() io.Reader  { return dc.stdin }
+func (dc *defaultContext) Stdout() io.Writer { return dc.stdout }
+
+// Environment returns a copy of the environment, with its own Args and Env so they can't be changed
+// through it
+func (dc *defaultContext) Environment() *Environment {
+	env := *dc.env
+	env.Args = append([]string(nil), env.Args...)
+	env.Env = append([]string(nil), env.Env...)
+	return &env
+}
+
+func (dc *defaultContext) DryRun() bool               { return dc.dryRun }
+func (dc *defaultContext) OutLog() *log.Logger        { return dc.outLog }
+func (dc *defaultContext) ErrLog() *log.Logger        { return dc.errLog }
+func (dc *defaultContext) Logger() *slog.Logger       { return dc.logger }
+func (dc *defaultContext) OutputFormat() string       { return OutputFormat(dc) }
+func (dc *defaultContext) Encode(v interface{}) error { return Encode(dc, v) }
+
+// Flags returns the parsed flags of the running command, or an empty FlagSet outside of one
+func (dc *defaultContext) Flags() *flag.FlagSet {
+	if dc.flags == nil {
+		return flag.NewFlagSet("", flag.ContinueOnError)
+	}
+	return dc.flags
+}
+
 type Program struct {
-	name         string
-	desc         string
-	root         Command
-	commands     []Command
-	env          *Environment
-	usage        func() string
-	calledCmd    string
-	printCmdHelp bool
+	// Out and Err log to the program's stdout and stderr, as set by WithStdout and WithStderr.
+	// Unlike the package level Out and Err they belong to the program, so output logged through
+	// them can be captured in tests and programs running side by side don't share them.
+	Out, Err *log.Logger
+
+	name           string
+	desc           string
+	root           Command
+	commands       []Command
+	env            *Environment
+	flags          *flag.FlagSet // program wide flags
+	usage          func(all bool) string
+	porcelain      bool
+	snapshot       bool
+	nsSeparator    string // separator between a command's namespace and name, e.g. ":" for db:migrate
+	helpTokens     []string
+	aliasUsage     bool // list aliases in the command table
+	prefixMatch    bool // match commands by unambiguous prefixes of their names
+	foldCase       bool // match command names ignoring case
+	exitCodes      map[error]int
+	middleware     []func(RunFunc) RunFunc
+	before         []func(*Environment, Command) error
+	after          []func(*Environment, Command, error)
+	builtins       []Command // commands provided by the program itself
+	envBinding     string    // prefix of the environment variables flags fall back to
+	strictEnv      bool      // check for unrecognized environment variables
+	strictEnvFatal bool      // set by -strict-env
+	nameFromArgs   bool      // run as the name the program was called by
+	transform      func(io.Writer) io.Writer
+	cacheTTL       time.Duration          // how long cached command results are replayed for
+	noCache        bool                   // set by -no-cache
+	history        bool                   // keep a usage history to rank suggestions by
+	progress       *choiceValue           // set by -progress
+	projectConfig  string                 // name of the file project default flags are loaded from
+	version        string                 // set by WithVersion
+	deprecated     map[string]deprecation // deprecated command names and where they forward to
+	posixShort     bool                   // expand clustered single letter flags
+	interspersed   bool                   // parse flags given after positional arguments
+	runFunc        RunFunc                // the RunFunc given to the last Run, used by Execute
+	timeout        time.Duration          // set by WithTimeout
+	unknownCommand func(env *Environment, name string, args []string) error
+	usageWidth     int  // set by WithUsageWidth, 0 to work it out and negative for no wrapping
+	debug          bool // set by -debug
+	style          UsageStyle
+	explain        bool // set by -explain
+	signalCancel   bool // cancel the context on SIGINT and SIGTERM
+	color          colorMode
+	usageTemplate  *template.Template
+	jsonOutput     bool                           // set by -json
+	config         map[string]map[string][]string // flag defaults by command path, set by LoadConfig
+	configPath     string
 }
 
-func NewProgram(name string, desc string, root Command, cmds []Command) (*Program, error) {
+// NewProgram returns a program called name running root when no command is given and cmds by name.
+// It fails when name is empty or there is nothing to run, with neither a root nor any commands.
+func NewProgram(name string, desc string, root Command, cmds []Command, opts ...Option) (*Program, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("program name must not be empty")
+	}
+	if root == nil && len(cmds) == 0 {
+		return nil, fmt.Errorf("%s: program needs a root command or at least one command", name)
+	}
+	for i, cmd := range cmds {
+		if cmd == nil {
+			return nil, fmt.Errorf("%s: command %d is nil", name, i)
+		}
+	}
+
 	wd, err := os.Getwd()
 	if err != nil {
 		return nil, fmt.Errorf("unable to get working directory: %v", err)
 	}
 
 	p := &Program{
-		name:     name,
-		desc:     desc,
-		root:     root,
-		commands: cmds,
+		name:       name,
+		desc:       desc,
+		root:       root,
+		commands:   cmds,
+		helpTokens: defaultHelpTokens,
+		style:      defaultUsageStyle,
 		env: &Environment{
 			WorkingDir: wd,
 			Env:        os.Environ(),
+			stdin:      os.Stdin,
 			stdout:     os.Stdout,
 			stderr:     os.Stderr,
 		},
 	}
 
+	p.flags = flag.NewFlagSet(name, flag.ContinueOnError)
+	p.flags.SetOutput(io.Discard)
+	p.flags.BoolVar(&p.porcelain, "porcelain", false, "Produce stable, machine-readable output")
+	p.flags.BoolVar(&p.explain, "explain", false, "Show what would run instead of running it")
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	p.env.outLog = log.New(envWriter{&p.env.stdout}, "", 0)
+	p.env.errLog = log.New(envWriter{&p.env.stderr}, "", 0)
+	p.Out, p.Err = p.env.outLog, p.env.errLog
+	if p.env.logger == nil {
+		p.env.logger = newTextLogger(envWriter{&p.env.stderr})
+	}
+
+	p.builtins = []Command{
+		&commandsCommand{p: p},
+		&shellCompletionCommand{p: p},
+		&helpCommand{p: p},
+	}
+	if p.version != "" {
+		p.builtins = append(p.builtins, &versionCommand{p: p})
+	}
+
 	p.createProgramUsage()
 
 	return p, nil
 }
 
+// envWriter writes to whatever an environment writer currently points at, so loggers keep up as
+// stdout is transformed or captured
+type envWriter struct {
+	w *io.Writer
+}
+
+func (ew envWriter) Write(b []byte) (int, error) { return (*ew.w).Write(b) }
+
 func (p *Program) createProgramUsage() {
-	p.usage = func() string {
+	p.usage = func(all bool) string {
 		var u bytes.Buffer
+		p.writeProgramUsage(&u, all)
+		return u.String()
+	}
+}
 
-		if len(p.commands) > 0 {
-			fmt.Fprintf(&u, "Usage: %s <command>\n", p.name)
-			fmt.Fprintln(&u, "")
-			if len(p.desc) > 0 {
-				fmt.Fprintln(&u, strings.TrimSpace(p.desc))
-				fmt.Fprintln(&u, "")
-			}
-			fmt.Fprintln(&u, "Commands:")
-			fmt.Fprintln(&u, "")
-			w := tabwriter.NewWriter(&u, 0, 0, 2, ' ', 0)
-			if p.root != nil {
-				fmt.Fprintf(w, "\t[default]\t%s\n", p.root.Name())
-			}
-			for _, cmd := range p.commands {
-				fmt.Fprintf(w, "\t%s\t%s\n", cmd.Name(), cmd.Desc())
-			}
-			w.Flush()
-			fmt.Fprintln(&u, "")
-		} else {
-			fs := flag.NewFlagSet(p.root.Name(), flag.ContinueOnError)
-			p.root.Register(fs)
-			fmt.Fprintln(&u, strings.TrimSpace(p.createCommandUsage(fs, p.root)))
-		}
+// WriteUsage writes the program usage to w as it is rendered, stopping at the first failed write,
+// such as when w is a pipe whose reader has gone away.
+func (p *Program) WriteUsage(w io.Writer) error {
+	return p.writeProgramUsage(w, false)
+}
+
+// writeProgramUsage writes the program usage to w, including hidden commands and flags when all is
+// set
+func (p *Program) writeProgramUsage(w io.Writer, all bool) error {
+	u := &stickyWriter{w: w}
+
+	if p.porcelain {
+		p.writePorcelainCommands(u)
+		return u.err
+	}
 
-		if len(p.commands) > 0 {
-			fmt.Fprintf(&u, "Use \"%s help [command]\" for more information about a command.\n", p.name)
+	if p.usageTemplate != nil {
+		if err := p.usageTemplate.Execute(u, p.usageData(all)); err != nil {
+			return err
 		}
+		return u.err
+	}
 
-		return u.String()
+	if len(p.commands) == 0 {
+		p.writeCommandUsage(u, p.scratchFlagSet(p.root), p.root, all)
+		return u.err
+	}
+
+	if p.root != nil {
+		fmt.Fprintf(u, "%s %s [command]\n", p.paint("Usage:", ansiYellow), p.name)
+	} else {
+		fmt.Fprintf(u, "%s %s <command>\n", p.paint("Usage:", ansiYellow), p.name)
+	}
+	fmt.Fprintln(u, "")
+	if len(p.desc) > 0 {
+		fmt.Fprintln(u, wrapText(strings.TrimSpace(p.desc), p.wrapWidth()))
+		fmt.Fprintln(u, "")
+	}
+	if p.root != nil {
+		fmt.Fprintln(u, p.style.Heading("Default"))
+		fmt.Fprintln(u, "")
+		t := p.usageTable()
+		t.row(p.style.Indent+p.paint(p.root.Name(), ansiBold), p.root.Desc()+" (runs when no command is given)")
+		t.write(u)
+		fmt.Fprintln(u, "")
 	}
+	fmt.Fprintln(u, p.style.Heading("Commands"))
+	fmt.Fprintln(u, "")
+	cmds, namespaces := p.groupByNamespace(visibleCommands(p.allCommands(), all))
+	t := p.usageTable()
+	for _, cmd := range cmds {
+		t.row(p.style.Indent+p.paint(p.tableName(cmd), ansiBold), cmd.Desc())
+	}
+	t.write(u)
+	fmt.Fprintln(u, "")
+	for _, ns := range namespaces {
+		fmt.Fprintln(u, p.style.Heading(ns.name))
+		fmt.Fprintln(u, "")
+		t := p.usageTable()
+		for _, cmd := range ns.commands {
+			t.row(p.style.Indent+p.paint(p.tableName(cmd), ansiBold), cmd.Desc())
+		}
+		t.write(u)
+		fmt.Fprintln(u, "")
+	}
+	if t := p.flagTable(p.flags, all, nil); len(t.rows) > 0 {
+		fmt.Fprintln(u, p.style.Heading("Flags"))
+		fmt.Fprintln(u, "")
+		t.write(u)
+		fmt.Fprintln(u, "")
+	}
+	fmt.Fprintf(u, "Use \"%s help [command]\" for more information about a command.\n", p.name)
+
+	return u.err
 }
 
+// ErrParseArgs is matched, with errors.Is, by the ErrParseFlags returned when a command's flags
+// can't be parsed.
 var ErrParseArgs = errors.New("could not parse arguments")
 
-func (p *Program) Run(args []string, fn func(*Environment, Command, []string) error) error {
+func (p *Program) Run(args []string, fn RunFunc) error {
+	if p.nameFromArgs && len(args) > 0 {
+		return p.RunAs(filepath.Base(args[0]), args, fn)
+	}
+	return p.run(args, fn)
+}
+
+// RunAs runs the program as if it were called name, whatever args[0] is, for binaries installed
+// under several names such as busybox. When name is one of the program's commands that command is
+// run as the only command, so "farewell -h" behaves as if farewell were its own program. Otherwise
+// name is used as the program name in usage and errors.
+func (p *Program) RunAs(name string, args []string, fn RunFunc) error {
+	prevName, prevRoot, prevCommands := p.name, p.root, p.commands
+	defer func() {
+		p.name, p.root, p.commands = prevName, prevRoot, prevCommands
+	}()
+
+	p.name = name
+	for _, cmd := range p.commands {
+		if cmd.Name() == name {
+			p.root, p.commands = cmd, nil
+			break
+		}
+	}
+	return p.run(args, fn)
+}
+
+func (p *Program) run(args []string, fn RunFunc) error {
+	p.runFunc = fn
+	p.resetGlobalFlags()
+	defer p.keepWorkingDir()()
+	args, err := p.parseGlobalFlags(args, true)
+	if err != nil {
+		return err
+	}
+
+	if err := p.checkEnv(); err != nil {
+		return err
+	}
+
 	p.env.Args = args
-	if err := p.parseArgs(args); err != nil {
+	cmd, cmdArgs, help, err := p.resolve(args)
+	unknown := p.isUnknownCommand(err, args)
+	if err != nil && !unknown {
 		return err
 	}
 
-	for _, cmd := range p.commands {
-		if cmd.Name() == p.calledCmd {
-			fs := flag.NewFlagSet(p.calledCmd, flag.ContinueOnError)
-			fs.SetOutput(p.env.stderr)
-			cmd.Register(fs)
+	if p.signalCancel {
+		defer p.env.withSignals()()
+	}
 
-			fs.Usage = func() {
-				Err.Print(p.createCommandUsage(fs, cmd))
-			}
+	if unknown {
+		return p.unknownCommand(p.env, args[1], args[2:])
+	}
+	return p.runCommand(cmd, cmdArgs, help, fn)
+}
 
-			if p.printCmdHelp {
-				fs.Usage()
-				return nil
-			}
+// Resolve reports which command Run would dispatch args to, without running it or parsing its
+// flags. It returns the command, the args it would be given, whether help was requested for it and
+// any error resolving it.
+func (p *Program) Resolve(args []string) (Command, []string, bool, error) {
+	args, err := p.parseGlobalFlags(args, false)
+	if err != nil {
+		return nil, nil, false, err
+	}
 
-			if err := fs.Parse(p.env.Args[2:]); err != nil {
-				return ErrParseArgs
-			}
+	cmd, cmdArgs, help, err := p.resolve(args)
+	return cmd, cmdArgs, help != noHelp, err
+}
 
-			return fn(p.env, cmd, fs.Args())
-		}
+// resolve matches args, stripped of any leading program flags, to a command. Help for the default
+// command is asked for by its name, "prog help greet", as the default command isn't one of the
+// program's commands.
+func (p *Program) resolve(args []string) (Command, []string, helpMode, error) {
+	if len(args) > 1 && p.isVersionFlag(args[1]) {
+		return &versionCommand{p: p}, args[2:], noHelp, nil
 	}
 
-	if p.calledCmd == defaultCommand && p.root != nil {
-		fs := flag.NewFlagSet(p.calledCmd, flag.ContinueOnError)
-		fs.SetOutput(p.env.stderr)
-		p.root.Register(fs)
+	called, help, err := p.parseArgs(args)
+	if err != nil {
+		return nil, nil, noHelp, err
+	}
 
-		fs.Usage = func() {
-			Err.Print(p.createCommandUsage(fs, p.root))
+	offset := 2
+	if help != noHelp {
+		offset = 3
+		if isAllFlag(args[2]) {
+			offset = 4
 		}
+	}
 
-		if p.printCmdHelp {
-			fs.Usage()
-			return nil
+	cmd, err := p.findCommand(called)
+	if err != nil {
+		return nil, nil, noHelp, err
+	} else if cmd != nil {
+		if err := p.checkDeprecated(called, cmd); err != nil {
+			return nil, nil, noHelp, err
 		}
+		rest := args[offset:]
+		if p.isPatternMatch(cmd, called) {
+			rest = args[offset-1:]
+		}
+		cmd, rest, err := p.descend(cmd, rest)
+		if err != nil {
+			return nil, nil, noHelp, err
+		}
+		return cmd, rest, help, nil
+	}
 
-		if err := fs.Parse(p.env.Args[1:]); err != nil {
-			return ErrParseArgs
+	if help != noHelp && p.root != nil && called == p.root.Name() {
+		return p.root, args[offset:], help, nil
+	}
+
+	if called == defaultCommand && p.root != nil {
+		return p.root, args[1:], help, nil
+	} else if called == defaultCommand && p.root == nil {
+		return nil, nil, noHelp, &ErrNoDefaultCommand{
+			usage: p.usage(false),
 		}
+	}
+
+	return nil, nil, noHelp, p.noSuchCommand(called)
+}
+
+// runCommand registers and parses the flags for cmd before handing it to fn
+func (p *Program) runCommand(cmd Command, args []string, help helpMode, fn RunFunc) (err error) {
+	fs, builtin := p.commandFlagSet(cmd, p.env.stderr)
+
+	fs.Usage = func() {
+		p.printCommandUsage(p.Err.Writer(), fs, cmd, false)
+	}
 
-		return fn(p.env, p.root, fs.Args())
-	} else if p.calledCmd == defaultCommand && p.root == nil {
-		return &ErrNoDefaultCommand{
-			usage: p.usage(),
+	if help == noHelp && p.helpRequested(fs, args) {
+		help = helpUsage
+	}
+
+	if help != noHelp {
+		all := help == helpAll
+		for _, arg := range args {
+			all = all || isAllFlag(arg)
 		}
+		p.printCommandUsage(p.Out.Writer(), fs, cmd, all)
+		return ErrHelpRequested
+	}
+
+	if isGroupOnly(cmd) {
+		fs.Usage()
+		return nil
+	}
+
+	defaults, err := p.projectArgs(fs, cmd)
+	if err != nil {
+		return err
+	}
+
+	if p.interspersed {
+		args = intersperseFlags(fs, args, p.posixShort)
+	}
+	if p.posixShort {
+		defaults, args = expandShortFlags(fs, defaults), expandShortFlags(fs, args)
+	}
+
+	// only the flags typed on the command line count as given, for conflicts between them and so
+	// they replace project defaults they conflict with
+	given := givenFlags(fs, args)
+	args = append(p.withoutConflicts(fs, cmd, defaults, given), args...)
+
+	// flag parsing stops at "--", which is dropped, leaving what follows untouched in fs.Args().
+	// Nothing before this point looks past a "--" either: help flags, short flag clusters,
+	// interspersed flags and group subcommands are only looked for ahead of it.
+	if err := fs.Parse(args); err != nil {
+		return &ErrParseFlags{commandName: p.commandPath(cmd), cause: err}
 	}
 
-	return nil
+	if err := p.checkConflicts(fs, cmd, given); err != nil {
+		return err
+	}
+
+	if err := p.applyEnv(fs); err != nil {
+		return err
+	}
+
+	if err := p.applyConfig(cmd, fs); err != nil {
+		return err
+	}
+
+	if err := p.validateArgs(cmd, fs.Args()); err != nil {
+		fs.Usage()
+		return err
+	}
+
+	defer p.env.withFlags(fs)()
+
+	if p.explain {
+		return p.explainCommand(p.env.stdout, fs, cmd)
+	}
+
+	defer p.emitEvents()()
+
+	if err := p.checkPreconditions(p.env.GetDefaultContext(), cmd); err != nil {
+		return err
+	}
+
+	if prompt := confirmationPrompt(cmd); prompt != "" && !builtin.yes {
+		if err := p.env.confirm(prompt); err != nil {
+			return err
+		}
+	}
+
+	if builtin.output != nil {
+		defer p.env.withOutputFormat(builtin.output.value)()
+	}
+	if p.jsonOutput {
+		defer p.env.withOutputFormat("json")()
+	}
+
+	defer p.withSubcommands(fn)()
+
+	if n := maxRetries(cmd); n > 0 {
+		fn = retrying(n, fn)
+	}
+	if builtin.timeout > 0 {
+		fn = timingOut(builtin.timeout, fn)
+	}
+
+	if p.transform != nil && p.env.depth == 0 {
+		restore := p.env.transformStdout(p.transform)
+		defer func() {
+			if ferr := restore(); err == nil {
+				err = ferr
+			}
+		}()
+	}
+
+	if path, ok := p.cachePath(fs, cmd); ok {
+		fn = p.caching(path, fn)
+	}
+
+	run := p.chain(fn)
+	if p.debug {
+		run = p.tracing(fs, run)
+	}
+	err = run(p.env, cmd, fs.Args())
+	if errors.Is(err, ErrShowUsage) {
+		fs.Usage()
+		return nil
+	}
+	return err
 }
 
+// ErrHelpRequested is returned by Run once it has printed the help asked for, such as with
+// "prog help deploy" or "prog deploy -h", so callers can tell help apart from a successful run.
+// ExitCode maps it to 0 and FormatError to an empty message. The program usage, asked for with
+// "prog help", is returned as an error holding the usage, which also matches it with errors.Is.
+var ErrHelpRequested = errors.New("help requested")
+
+// ErrShowUsage can be returned from a command's Run to have its usage printed instead, for commands
+// such as groups that have nothing to do when run on their own.
+var ErrShowUsage = errors.New("show usage")
+
 // ErrNoDefaultCommand is returned when the default command is called but no command is provided to
 // handle it.
 type ErrNoDefaultCommand struct {
@@ -193,6 +660,16 @@ func (e *ErrNoDefaultCommand) Error() string {
 	return e.usage
 }
 
+// defaultString returns the default value of f as shown in usage. Values such as slices and maps
+// whose String is hard to read can implement DefaultString() string to render it, e.g. as "a,b,c"
+// or "k=v,k=v".
+func defaultString(f *flag.Flag) string {
+	if d, ok := f.Value.(interface{ DefaultString() string }); ok {
+		return d.DefaultString()
+	}
+	return f.DefValue
+}
+
 // prettyDefaultValue sets the default value to `<none>` if it is blank
 func prettyDefaultValue(s string) (dv string) {
 	dv = s
@@ -202,108 +679,319 @@ func prettyDefaultValue(s string) (dv string) {
 	return dv
 }
 
-func (p *Program) createCommandUsage(fs *flag.FlagSet, cmd Command) string {
+// createCommandUsage renders the usage of cmd, including its hidden flags when all is set
+func (p *Program) createCommandUsage(fs *flag.FlagSet, cmd Command, all bool) string {
+	var usage bytes.Buffer
+	p.writeCommandUsage(&usage, fs, cmd, all)
+	fmt.Fprintln(&usage, "")
+	return usage.String()
+}
+
+// printCommandUsage writes the usage of cmd straight to w, as it is rendered. Help that was asked
+// for goes to stdout so it can be piped, usage shown for a mistake goes to stderr.
+func (p *Program) printCommandUsage(w io.Writer, fs *flag.FlagSet, cmd Command, all bool) {
+	if p.writeCommandUsage(w, fs, cmd, all) == nil {
+		fmt.Fprintln(w, "")
+	}
+}
+
+// writeCommandUsage writes the usage of cmd to w, each section separated by a blank line, stopping
+// at the first failed write
+func (p *Program) writeCommandUsage(w io.Writer, fs *flag.FlagSet, cmd Command, all bool) error {
+	u := &stickyWriter{w: w}
+
+	fmt.Fprintf(u, "%s %s\n", p.paint("Usage:", ansiYellow), p.synopsis(fs, cmd))
+
+	fmt.Fprintln(u, "")
+	fmt.Fprintln(u, wrapText(strings.TrimSpace(cmd.Help()), p.wrapWidth()))
+	if aliases := commandAliases(cmd); len(aliases) > 0 {
+		fmt.Fprintln(u, "")
+		fmt.Fprintf(u, "Aliases: %s\n", strings.Join(aliases, ", "))
+	}
+	if children := visibleCommands(subcommands(cmd), all); len(children) > 0 {
+		fmt.Fprintln(u, "")
+		fmt.Fprintln(u, p.style.Heading("Commands"))
+		fmt.Fprintln(u, "")
+		t := p.usageTable()
+		for _, child := range children {
+			t.row(p.style.Indent+p.paint(p.tableName(child), ansiBold), child.Desc())
+		}
+		t.write(u)
+	}
+	shown := func(f *flag.Flag) bool {
+		return !p.isGlobalFlag(f) || p.isDryRunFlag(f)
+	}
+	var grouped []func(*flag.Flag) bool
+	for _, g := range flagGroups(cmd) {
+		in := inFlagGroup(fs, g)
+		grouped = append(grouped, in)
+		if t := p.flagTable(fs, all, func(f *flag.Flag) bool { return shown(f) && in(f) }); len(t.rows) > 0 {
+			fmt.Fprintln(u, "")
+			fmt.Fprintln(u, p.style.Heading(g.Title))
+			fmt.Fprintln(u, "")
+			t.write(u)
+		}
+	}
+	t := p.flagTable(fs, all, func(f *flag.Flag) bool {
+		for _, in := range grouped {
+			if in(f) {
+				return false
+			}
+		}
+		return shown(f)
+	})
+	if len(t.rows) > 0 {
+		fmt.Fprintln(u, "")
+		fmt.Fprintln(u, p.style.Heading("Flags"))
+		fmt.Fprintln(u, "")
+		t.write(u)
+	}
+	if examples := commandExamples(cmd); len(examples) > 0 {
+		fmt.Fprintln(u, "")
+		fmt.Fprintln(u, p.style.Heading("Examples"))
+		fmt.Fprintln(u, "")
+		p.writeExamples(u, examples)
+	}
+
+	return u.err
+}
+
+// flagUsage renders the flags of fs as a table, pairing the names of the same flag. Hidden
+// flags are left out unless all is set and when include is non-nil only the flags it accepts are
+// rendered.
+func (p *Program) flagUsage(fs *flag.FlagSet, all bool, include func(*flag.Flag) bool) string {
+	var fb bytes.Buffer
+	p.flagTable(fs, all, include).write(&fb)
+	return fb.String()
+}
+
+// flagTable lays out the flags of fs chosen as for flagUsage in a table
+func (p *Program) flagTable(fs *flag.FlagSet, all bool, include func(*flag.Flag) bool) *table {
+	t := p.usageTable()
+	for _, group := range groupFlags(fs, all, include) {
+		names := make([]string, len(group))
+		for i, f := range group {
+			names[i] = "-" + f.Name
+		}
+		f := group[len(group)-1]
+		def := prettyDefaultValue(defaultString(f))
+		for _, gf := range group {
+			if isDynamicDefault(gf) {
+				def += ", auto"
+				break
+			}
+		}
+		if p.envBinding != "" {
+			def += ", env: " + p.envKey(longestName(group))
+		}
+		t.row(p.style.Indent+p.paint(strings.Join(names, " "), ansiCyan), fmt.Sprintf("%s (default: %s)", f.Usage, def))
+	}
+	return t
+}
+
+// groupFlags groups the visible flags of fs that are the same flag under several names, such as -p
+// and -pirate, identified by flagKey. Groups come first followed by the lone flags, each in the
+// order they are visited. Hidden flags are only grouped when all is set, and when include is
+// non-nil only the flags it accepts are grouped.
+func groupFlags(fs *flag.FlagSet, all bool, include func(*flag.Flag) bool) [][]*flag.Flag {
 	var (
-		usage bytes.Buffer
-		flags bool
-		fb    bytes.Buffer
-		fw    = tabwriter.NewWriter(&fb, 0, 4, 2, ' ', 0)
+		byKey  = make(map[interface{}][]*flag.Flag)
+		order  []interface{} // keys in the order their first flag was visited
+		paired []interface{} // keys in the order they got a second flag
 	)
-
-	hold := make(map[string]*flag.Flag)
 	fs.VisitAll(func(f *flag.Flag) {
-		flags = true
-		if hf, ok := hold[f.Usage]; ok {
-			fmt.Fprintf(fw, "\t-%s -%s\t%s (default: %s)\n", hf.Name, f.Name, f.Usage, prettyDefaultValue(f.DefValue))
-			delete(hold, f.Usage)
-		} else {
-			hold[f.Usage] = f
+		if (!all && isHiddenFlag(f)) || (include != nil && !include(f)) {
 			return
 		}
+		key := flagKey(f)
+		byKey[key] = append(byKey[key], f)
+		switch len(byKey[key]) {
+		case 1:
+			order = append(order, key)
+		case 2:
+			paired = append(paired, key)
+		}
 	})
-	for _, f := range hold {
-		fmt.Fprintf(fw, "\t-%s\t%s (default: %s)\n", f.Name, f.Usage, prettyDefaultValue(f.DefValue))
-	}
-	fw.Flush()
 
-	if p.root != nil && p.root.Name() == cmd.Name() {
-		fmt.Fprintf(&usage, "Usage: %s %s\n", p.name, cmd.Args())
-	} else {
-		fmt.Fprintf(&usage, "Usage: %s %s %s\n", p.name, cmd.Name(), cmd.Args())
+	var groups [][]*flag.Flag
+	for _, key := range paired {
+		groups = append(groups, byKey[key])
+	}
+	for _, key := range order {
+		if len(byKey[key]) == 1 {
+			groups = append(groups, byKey[key])
+		}
 	}
+	return groups
+}
 
-	fmt.Fprintln(&usage, "")
-	fmt.Fprintln(&usage, strings.TrimSpace(cmd.Help()))
-	fmt.Fprintln(&usage, "")
-	if flags {
-		fmt.Fprintln(&usage, "Flags:")
-		fmt.Fprintln(&usage, "")
-		fmt.Fprintln(&usage, fb.String())
+// flagKey returns what the names of the same flag have in common: the variable their Value points
+// at, so -p and -pirate bound to the same bool are paired whatever their usage says. Values that
+// aren't pointers are keyed by their usage instead.
+func flagKey(f *flag.Flag) interface{} {
+	v := f.Value
+	if d, ok := v.(*dynamicDefault); ok {
+		v = d.Value
 	}
 
-	return usage.String()
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr {
+		return f.Usage
+	}
+	return struct {
+		t   reflect.Type
+		ptr uintptr
+	}{rv.Type(), rv.Pointer()}
 }
 
 const defaultCommand = "default"
 
+// defaultHelpTokens are the arguments that trigger help unless changed with WithHelpTokens
+var defaultHelpTokens = []string{"help", "-help", "--help", "-h"}
+
 // isHelp checks whether the provided args is for help
-func isHelp(arg string) bool {
-	return strings.Contains(strings.ToLower(arg), "help") || strings.ToLower(arg) == "-h"
+func (p *Program) isHelp(arg string) bool {
+	for _, t := range p.helpTokens {
+		if strings.EqualFold(arg, t) {
+			return true
+		}
+	}
+	return false
 }
 
-// isCommand checks if the provided arg is a command
-func isCommand(arg string, cmds []Command) bool {
-	for _, cmd := range cmds {
-		if cmd.Name() == arg {
+// helpRequested checks whether args, given after the command name, hold a help flag such as
+// "--help". Values of the command's flags and anything after a "--" terminator are skipped, as are
+// help flags the command registers itself.
+func (p *Program) helpRequested(fs *flag.FlagSet, args []string) bool {
+	for i := 0; i < len(args); i++ {
+		name, _, hasValue := splitFlag(args[i])
+		if args[i] == "--" {
+			return false
+		}
+		if name == "" {
+			continue
+		}
+
+		f := fs.Lookup(name)
+		switch {
+		case f == nil && p.isHelp(args[i]):
 			return true
+		case f != nil && !hasValue && !isBoolFlag(f):
+			i++
 		}
 	}
 	return false
 }
 
-func (p *Program) parseArgs(args []string) error {
+// isCommand checks if the provided arg is a command
+func (p *Program) isCommand(arg string) (bool, error) {
+	cmd, err := p.findCommand(arg)
+	return cmd != nil, err
+}
+
+// parseArgs works out the name of the called command and whether help was requested for it
+func (p *Program) parseArgs(args []string) (called string, help helpMode, err error) {
 	switch len(args) {
 	case 0, 1:
-		p.calledCmd = defaultCommand
+		called = defaultCommand
 	case 2:
-		if isHelp(args[1]) {
-			return fmt.Errorf(p.usage())
-		} else if isCommand(args[1], p.commands) {
-			p.calledCmd = args[1]
+		if p.isHelpToken(args[1]) {
+			return "", noHelp, &usageError{usage: p.usage(false)}
+		} else if ok, err := p.isCommand(args[1]); err != nil {
+			return "", noHelp, err
+		} else if ok {
+			called = args[1]
 		} else if p.root != nil {
-			p.calledCmd = defaultCommand
+			called = defaultCommand
 		} else {
-			return &ErrNoSuchCommand{
-				programName: p.name,
-				commandName: args[1],
-			}
+			return "", noHelp, p.noSuchCommand(args[1])
 		}
 	default:
-		if isHelp(args[1]) {
-			p.calledCmd = args[2]
-			p.printCmdHelp = true
-		} else if isCommand(args[1], p.commands) {
-			p.calledCmd = args[1]
+		if p.isHelpToken(args[1]) && isAllFlag(args[2]) {
+			if len(args) == 3 {
+				return "", noHelp, &usageError{usage: p.usage(true)}
+			}
+			called = args[3]
+			help = helpAll
+		} else if p.isHelpToken(args[1]) {
+			called = args[2]
+			help = helpUsage
+		} else if ok, err := p.isCommand(args[1]); err != nil {
+			return "", noHelp, err
+		} else if ok {
+			called = args[1]
 		} else if p.root != nil {
-			p.calledCmd = defaultCommand
+			called = defaultCommand
 		} else {
-			return &ErrNoSuchCommand{
-				programName: p.name,
-				commandName: args[1],
-			}
+			return "", noHelp, p.noSuchCommand(args[1])
 		}
 	}
 
-	return nil
+	return called, help, nil
 }
 
-// ErrNoSuchCommand is returned when the requested command is not found
+// noSuchCommand returns the error for an unknown command name, with any suggestions for it
+func (p *Program) noSuchCommand(name string) *ErrNoSuchCommand {
+	return &ErrNoSuchCommand{
+		programName: p.name,
+		commandName: name,
+		suggestions: p.suggestCommands(name),
+	}
+}
+
+// ErrNoSuchCommand is returned when the requested command is not found. When there are commands
+// close to the one requested they are suggested:
+//
+//	prog: gret: no such command, did you mean "greet"?
+//
+// or with several close commands:
+//
+//	prog: d: no such command, did you mean:
+//
+//	  deploy
+//	  diff
 type ErrNoSuchCommand struct {
 	programName string
 	commandName string
+	suggestions []string
 }
 
 // Error implements the error interface
 func (e *ErrNoSuchCommand) Error() string {
-	return fmt.Sprintf("%s: %s: no such command", e.programName, e.commandName)
+	if len(e.suggestions) == 0 {
+		return fmt.Sprintf("%s: %s: no such command", e.programName, e.commandName)
+	}
+
+	if len(e.suggestions) == 1 {
+		return fmt.Sprintf("%s: %s: no such command, did you mean %q?", e.programName, e.commandName, e.suggestions[0])
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %s: no such command, did you mean:\n", e.programName, e.commandName)
+	for _, s := range e.suggestions {
+		fmt.Fprintf(&b, "\n  %s", s)
+	}
+	return b.String()
+}
+
+// ProgramName returns the name of the program the command was looked for in
+func (e *ErrNoSuchCommand) ProgramName() string {
+	return e.programName
+}
+
+// CommandName returns the command name that was given, as it was given
+func (e *ErrNoSuchCommand) CommandName() string {
+	return e.commandName
+}
+
+// Is reports whether target is an *ErrNoSuchCommand for the same program and command, with empty
+// names in target matching any, so errors.Is(err, &ErrNoSuchCommand{}) matches every unknown
+// command
+func (e *ErrNoSuchCommand) Is(target error) bool {
+	t, ok := target.(*ErrNoSuchCommand)
+	if !ok {
+		return false
+	}
+	return (t.programName == "" || t.programName == e.programName) &&
+		(t.commandName == "" || t.commandName == e.commandName)
 }
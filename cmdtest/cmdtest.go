@@ -0,0 +1,81 @@
+// Package cmdtest runs a single cmd.Command in memory for tests, capturing what it writes:
+//
+//	stdout, _, err := cmdtest.RunCommand(&greetCommand{}, []string{"-p", "world"})
+//	if err != nil || stdout != "Ahoy, world!\n" {
+//		t.Fatalf("got %q, %v", stdout, err)
+//	}
+package cmdtest
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/benhinchley/cmd"
+)
+
+// config is what a RunCommand call is set up with
+type config struct {
+	stdin io.Reader
+	env   []string
+	dir   string
+}
+
+// Option configures a RunCommand call.
+type Option func(*config)
+
+// WithStdin sets what the command reads from stdin, which is empty by default.
+func WithStdin(r io.Reader) Option {
+	return func(c *config) {
+		c.stdin = r
+	}
+}
+
+// WithEnv sets the environment the command runs in as "key=value" pairs, which is empty by default
+// rather than the environment of the test.
+func WithEnv(env ...string) Option {
+	return func(c *config) {
+		c.env = env
+	}
+}
+
+// WithWorkingDir sets the working directory the command is given, which is the test's own by
+// default.
+func WithWorkingDir(dir string) Option {
+	return func(c *config) {
+		c.dir = dir
+	}
+}
+
+// RunCommand registers the flags of c, parses args with them and runs c with what is left,
+// returning what it wrote to its Context's stdout and the program's stderr along with its error.
+// Usage printed for -h, along with the error cmd.ErrHelpRequested, ends up in stdout and usage
+// printed for a bad flag in stderr. Output written to the package level cmd.Out and cmd.Err isn't
+// captured.
+func RunCommand(c cmd.Command, args []string, opts ...Option) (stdout, stderr string, err error) {
+	conf := &config{stdin: &bytes.Buffer{}, env: []string{}}
+	for _, opt := range opts {
+		opt(conf)
+	}
+
+	var out, errOut bytes.Buffer
+	popts := []cmd.Option{
+		cmd.WithStdin(conf.stdin),
+		cmd.WithStdout(&out),
+		cmd.WithStderr(&errOut),
+		cmd.WithColor(false),
+		cmd.WithEnviron(conf.env),
+	}
+	if conf.dir != "" {
+		popts = append(popts, cmd.WithWorkingDir(conf.dir))
+	}
+
+	p, err := cmd.NewProgram(c.Name(), "", c, nil, popts...)
+	if err != nil {
+		return "", "", err
+	}
+
+	err = p.Run(append([]string{c.Name()}, args...), func(env *cmd.Environment, c cmd.Command, args []string) error {
+		return c.Run(env.GetDefaultContext(), args)
+	})
+	return out.String(), errOut.String(), err
+}
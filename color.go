@@ -0,0 +1,49 @@
+package cmd
+
+// colorMode is whether the usage is colored
+type colorMode int
+
+const (
+	colorAuto   colorMode = iota // on a terminal when NO_COLOR isn't set
+	colorAlways                  // forced on with WithColor
+	colorNever                   // forced off with WithColor
+)
+
+// ANSI SGR parameters used in the usage
+const (
+	ansiBold   = "1"
+	ansiYellow = "33"
+	ansiCyan   = "36"
+)
+
+// WithColor forces the colors of the usage on or off. By default the "Usage:" label is yellow,
+// command names bold and flag names cyan when stdout and stderr are both terminals, as the usage
+// goes to either, unless the NO_COLOR environment variable is set or snapshot mode is enabled.
+func WithColor(enabled bool) Option {
+	return func(p *Program) {
+		if enabled {
+			p.color = colorAlways
+		} else {
+			p.color = colorNever
+		}
+	}
+}
+
+// colorEnabled checks whether the usage should be colored
+func (p *Program) colorEnabled() bool {
+	switch p.color {
+	case colorAlways:
+		return true
+	case colorNever:
+		return false
+	}
+	return !p.snapshotMode() && p.env.getenv("NO_COLOR") == "" && p.interactive()
+}
+
+// paint wraps s in the ANSI escape codes for the SGR parameter code, when color is enabled
+func (p *Program) paint(s, code string) string {
+	if s == "" || !p.colorEnabled() {
+		return s
+	}
+	return "\x1b[" + code + "m" + s + "\x1b[0m"
+}
@@ -0,0 +1,413 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// completionCommand is the completion data gathered for a single command
+type completionCommand struct {
+	name        string // path to the command, e.g. "remote add", empty for the top level
+	subcommands []completionItem
+	flags       []completionItem
+}
+
+// completionItem is a single command or flag to complete, along with its description
+type completionItem struct {
+	name    string
+	desc    string
+	value   bool     // the flag expects a value
+	choices []string // the values the flag is restricted to
+	aliases []string // other names of the command
+	hidden  bool     // the command is hidden, it is followed but not offered
+}
+
+// word returns the word to complete for the item, flags expecting a value complete as -name= so
+// the value can be typed straight after
+func (i completionItem) word() string {
+	if i.value {
+		return i.name + "="
+	}
+	return i.name
+}
+
+// GenerateCompletion writes a completion script for shell to w, completing command names and,
+// once a command has been chosen, its flags. Flags that take a value complete as -name= without a
+// trailing space so the value can be typed straight after. Supported shells are bash, zsh and fish,
+// zsh and fish show each command's and flag's description next to it. Hidden commands aren't
+// offered.
+//
+// The bash script also follows command groups down to their subcommands, completes aliases
+// alongside names, and completes the values of flags whose Value has a Choices() []string method.
+//
+// Scripts are written as UTF-8, with names quoted for the shell rather than escaped, so non-ASCII
+// command and flag names complete as they are.
+func (p *Program) GenerateCompletion(shell string, w io.Writer) error {
+	cmds := p.completionCommands()
+
+	switch shell {
+	case "bash":
+		return p.genBashCompletion(w, cmds)
+	case "zsh":
+		return p.genZshCompletion(w, topLevel(cmds))
+	case "fish":
+		return p.genFishCompletion(w, topLevel(cmds))
+	}
+	return fmt.Errorf("completion: unsupported shell %q", shell)
+}
+
+// completionCommands gathers the words to complete at the top level and for each command, down
+// through command groups
+func (p *Program) completionCommands() []*completionCommand {
+	top := &completionCommand{subcommands: completionSubcommands(p.allCommands())}
+	if p.root != nil {
+		top.flags = completionFlags(p.scratchFlagSet(p.root))
+	} else {
+		top.flags = completionFlags(p.flags)
+	}
+
+	cmds := []*completionCommand{top}
+	var walk func(path string, children []Command)
+	walk = func(path string, children []Command) {
+		for _, cmd := range children {
+			name := strings.TrimSpace(path + " " + cmd.Name())
+			cmds = append(cmds, &completionCommand{
+				name:        name,
+				subcommands: completionSubcommands(subcommands(cmd)),
+				flags:       completionFlags(p.scratchFlagSet(cmd)),
+			})
+			walk(name, subcommands(cmd))
+		}
+	}
+	walk("", p.allCommands())
+	return cmds
+}
+
+// completionSubcommands returns the items completing to cmds
+func completionSubcommands(cmds []Command) []completionItem {
+	var items []completionItem
+	for _, cmd := range cmds {
+		items = append(items, completionItem{
+			name:    cmd.Name(),
+			desc:    cmd.Desc(),
+			aliases: commandAliases(cmd),
+			hidden:  isHiddenCommand(cmd),
+		})
+	}
+	return items
+}
+
+// completionFlags returns the visible flags in fs
+func completionFlags(fs *flag.FlagSet) []completionItem {
+	var flags []completionItem
+	fs.VisitAll(func(f *flag.Flag) {
+		if isHiddenFlag(f) {
+			return
+		}
+		flags = append(flags, completionItem{
+			name:    "-" + f.Name,
+			desc:    f.Usage,
+			value:   !isBoolFlag(f),
+			choices: flagChoices(f),
+		})
+	})
+	return flags
+}
+
+// flagChoices returns the values f is restricted to, when its Value has a Choices() []string method
+func flagChoices(f *flag.Flag) []string {
+	if c, ok := f.Value.(interface{ Choices() []string }); ok {
+		return c.Choices()
+	}
+	return nil
+}
+
+// topLevel returns the commands of cmds that aren't in a group, with hidden commands left out of
+// the words offered, for the shells that don't follow groups
+func topLevel(cmds []*completionCommand) []*completionCommand {
+	var top []*completionCommand
+	for _, c := range cmds {
+		if strings.Contains(c.name, " ") {
+			continue
+		}
+		var subcommands []completionItem
+		for _, i := range c.subcommands {
+			if !i.hidden {
+				subcommands = append(subcommands, i)
+			}
+		}
+		top = append(top, &completionCommand{name: c.name, subcommands: subcommands, flags: c.flags})
+	}
+	return top
+}
+
+// completionFuncName returns a shell function name for the program
+func (p *Program) completionFuncName() string {
+	return "_" + strings.ToLower(strings.TrimSuffix(p.envPrefix(), "_")) + "_complete"
+}
+
+// genBashCompletion writes the bash script. It walks the words before the cursor to find the
+// command being completed, following groups and aliases and skipping flag values, then completes
+// the value of the flag before the cursor or the command's subcommands and flags. Names holding a
+// :, such as db:migrate, are completed whether or not : is in COMP_WORDBREAKS.
+func (p *Program) genBashCompletion(w io.Writer, cmds []*completionCommand) error {
+	var b strings.Builder
+	fn := p.completionFuncName()
+	valueFlags := strings.Join(bashValueFlagPatterns(cmds), "|")
+
+	fmt.Fprintf(&b, "# bash completion for %s\n", p.name)
+	fmt.Fprintf(&b, "%s() {\n", fn)
+
+	// a namespaced name such as db:migrate is split into three words by bash, as : is in
+	// COMP_WORDBREAKS, so the words are joined back together around each :
+	b.WriteString("\tlocal cw=() cword=0 i\n")
+	b.WriteString("\tfor ((i = 0; i < ${#COMP_WORDS[@]}; i++)); do\n")
+	b.WriteString("\t\tif ((i > 1)) && [[ ${COMP_WORDS[i]} == \":\" || ( ${cw[${#cw[@]}-1]} == *: && -n ${COMP_WORDS[i]} ) ]]; then\n")
+	b.WriteString("\t\t\tcw[${#cw[@]}-1]+=\"${COMP_WORDS[i]}\"\n")
+	b.WriteString("\t\telse\n")
+	b.WriteString("\t\t\tcw+=(\"${COMP_WORDS[i]}\")\n")
+	b.WriteString("\t\tfi\n")
+	b.WriteString("\t\t((i == COMP_CWORD)) && cword=$((${#cw[@]} - 1))\n")
+	b.WriteString("\tdone\n\n")
+
+	b.WriteString("\tlocal cur=\"${cw[cword]}\" prev=\"${cw[cword-1]}\" pre=\"\" path=\"\" words=\"\"\n")
+	b.WriteString("\tfor ((i = 1; i < cword; i++)); do\n")
+	b.WriteString("\t\tcase \"${cw[i]}\" in\n")
+	b.WriteString("\t\t=) ((i++)); continue ;;\n")
+	b.WriteString("\t\t-*=*) continue ;;\n")
+	b.WriteString("\t\tesac\n")
+	b.WriteString("\t\tcase \"$path:${cw[i]}\" in\n")
+	if valueFlags != "" {
+		fmt.Fprintf(&b, "\t\t%s)\n", valueFlags)
+		b.WriteString("\t\t\t[[ ${cw[i+1]} != \"=\" ]] && ((i++))\n")
+		b.WriteString("\t\t\tcontinue ;;\n")
+	}
+	b.WriteString("\t\t*:-*) continue ;;\n")
+	for _, c := range cmds {
+		for _, i := range c.subcommands {
+			var patterns []string
+			for _, n := range append([]string{i.name}, i.aliases...) {
+				patterns = append(patterns, shellQuote(c.name+":"+n))
+			}
+			fmt.Fprintf(&b, "\t\t%s) path=%s ;;\n", strings.Join(patterns, "|"), shellQuote(strings.TrimSpace(c.name+" "+i.name)))
+		}
+	}
+	b.WriteString("\t\t*) break ;;\n")
+	b.WriteString("\t\tesac\n")
+	b.WriteString("\tdone\n\n")
+
+	// a value typed as -flag=value is split into three words by bash, unless = has been taken out
+	// of COMP_WORDBREAKS
+	b.WriteString("\tif [[ $cur == \"=\" ]]; then\n")
+	b.WriteString("\t\tcur=\"\"\n")
+	b.WriteString("\telif [[ $prev == \"=\" ]]; then\n")
+	b.WriteString("\t\tprev=\"${cw[cword-2]}\"\n")
+	b.WriteString("\telif [[ $cur == -*=* ]]; then\n")
+	b.WriteString("\t\tprev=\"${cur%%=*}\"\n")
+	b.WriteString("\t\tpre=\"$prev=\"\n")
+	b.WriteString("\t\tcur=\"${cur#*=}\"\n")
+	b.WriteString("\tfi\n")
+	b.WriteString("\tcase \"$path:$prev\" in\n")
+	for _, c := range cmds {
+		for _, i := range c.flags {
+			if len(i.choices) > 0 {
+				fmt.Fprintf(&b, "\t%s)\n", shellQuote(c.name+":"+i.name))
+				fmt.Fprintf(&b, "\t\tCOMPREPLY=($(compgen -P \"$pre\" -W %s -- \"$cur\"))\n", shellQuote(strings.Join(i.choices, " ")))
+				b.WriteString("\t\treturn ;;\n")
+			}
+		}
+	}
+	if valueFlags != "" {
+		fmt.Fprintf(&b, "\t%s)\n", valueFlags)
+		b.WriteString("\t\treturn ;;\n")
+	}
+	b.WriteString("\tesac\n\n")
+
+	b.WriteString("\tcase \"$path\" in\n")
+	for _, c := range cmds {
+		var words []string
+		for _, i := range c.subcommands {
+			if !i.hidden {
+				words = append(words, i.name)
+				words = append(words, i.aliases...)
+			}
+		}
+		for _, i := range c.flags {
+			words = append(words, i.word())
+		}
+
+		fmt.Fprintf(&b, "\t%s)\n", shellQuote(c.name))
+		fmt.Fprintf(&b, "\t\twords=%s\n", shellQuote(strings.Join(words, " ")))
+		b.WriteString("\t\t;;\n")
+	}
+	b.WriteString("\tesac\n\n")
+	b.WriteString("\tCOMPREPLY=($(compgen -W \"$words\" -- \"$cur\"))\n")
+	// while : is a word break bash only replaces what follows the last : of the word
+	b.WriteString("\tif [[ $cur == *:* && $COMP_WORDBREAKS == *:* ]]; then\n")
+	b.WriteString("\t\tlocal colon=\"${cur%\"${cur##*:}\"}\"\n")
+	b.WriteString("\t\tCOMPREPLY=(\"${COMPREPLY[@]#\"$colon\"}\")\n")
+	b.WriteString("\tfi\n")
+	b.WriteString("\tif [[ ${#COMPREPLY[@]} -eq 1 && \"${COMPREPLY[0]}\" == *= ]]; then\n")
+	b.WriteString("\t\tcompopt -o nospace\n")
+	b.WriteString("\tfi\n")
+	b.WriteString("}\n")
+	fmt.Fprintf(&b, "complete -F %s %s\n", fn, p.name)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// bashValueFlagPatterns returns the case patterns matching "path:-flag" for every flag expecting
+// a value
+func bashValueFlagPatterns(cmds []*completionCommand) []string {
+	var patterns []string
+	for _, c := range cmds {
+		for _, i := range c.flags {
+			if i.value {
+				patterns = append(patterns, shellQuote(c.name+":"+i.name))
+			}
+		}
+	}
+	return patterns
+}
+
+func (p *Program) genZshCompletion(w io.Writer, cmds []*completionCommand) error {
+	var b strings.Builder
+	fn := p.completionFuncName()
+
+	fmt.Fprintf(&b, "#compdef %s\n", p.name)
+	fmt.Fprintf(&b, "# zsh completion for %s\n", p.name)
+	fmt.Fprintf(&b, "%s() {\n", fn)
+	b.WriteString("\tlocal cmd=\"\" i\n")
+	b.WriteString("\tlocal -a subcommands flags values\n")
+	b.WriteString("\tfor ((i = 2; i < CURRENT; i++)); do\n")
+	b.WriteString("\t\tcase \"${words[i]}\" in\n")
+	b.WriteString("\t\t-*) ;;\n")
+	b.WriteString("\t\t*) cmd=\"${words[i]}\"; break ;;\n")
+	b.WriteString("\t\tesac\n")
+	b.WriteString("\tdone\n\n")
+	b.WriteString("\tcase \"$cmd\" in\n")
+	for _, c := range cmds {
+		var commands, flags, values []string
+		for _, i := range c.subcommands {
+			commands = append(commands, zshDescribeItem(i))
+		}
+		for _, i := range c.flags {
+			if i.value {
+				values = append(values, zshDescribeItem(i))
+			} else {
+				flags = append(flags, zshDescribeItem(i))
+			}
+		}
+
+		fmt.Fprintf(&b, "\t%s)\n", shellQuote(c.name))
+		if len(commands) > 0 {
+			fmt.Fprintf(&b, "\t\tsubcommands=(%s)\n", shellQuoteAll(commands))
+			b.WriteString("\t\t_describe -t commands 'command' subcommands\n")
+		}
+		if len(flags) > 0 {
+			fmt.Fprintf(&b, "\t\tflags=(%s)\n", shellQuoteAll(flags))
+			b.WriteString("\t\t_describe -t flags 'flag' flags\n")
+		}
+		if len(values) > 0 {
+			fmt.Fprintf(&b, "\t\tvalues=(%s)\n", shellQuoteAll(values))
+			b.WriteString("\t\t_describe -t flags 'flag' values -S ''\n")
+		}
+		b.WriteString("\t\t;;\n")
+	}
+	b.WriteString("\tesac\n")
+	b.WriteString("}\n")
+	fmt.Fprintf(&b, "compdef %s %s\n", fn, p.name)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (p *Program) genFishCompletion(w io.Writer, cmds []*completionCommand) error {
+	var b strings.Builder
+	prog := fishQuote(p.name)
+
+	fmt.Fprintf(&b, "# fish completion for %s\n", p.name)
+	fmt.Fprintf(&b, "complete -c %s -f\n", prog)
+	for _, c := range cmds {
+		cond := "__fish_use_subcommand"
+		if c.name != "" {
+			cond = "__fish_seen_subcommand_from " + c.name
+		}
+
+		for _, i := range c.subcommands {
+			fmt.Fprintf(&b, "complete -c %s -n %s -a %s -d %s\n", prog, fishQuote(cond), fishQuote(i.name), fishQuote(completionDesc(i.desc)))
+		}
+		for _, i := range c.flags {
+			// fish calls single dash long flags old style options, given with -o
+			opt := "-o " + fishQuote(strings.TrimPrefix(i.name, "-"))
+			if i.value {
+				opt += " -r"
+			}
+			fmt.Fprintf(&b, "complete -c %s -n %s %s -d %s\n", prog, fishQuote(cond), opt, fishQuote(completionDesc(i.desc)))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// completionDesc collapses a description onto a single line
+func completionDesc(desc string) string {
+	return strings.Join(strings.Fields(desc), " ")
+}
+
+// zshDescribeItem formats i as a name:description entry for _describe, escaping colons in the name
+// so they aren't taken as the separator
+func zshDescribeItem(i completionItem) string {
+	return strings.ReplaceAll(i.word(), ":", `\:`) + ":" + completionDesc(i.desc)
+}
+
+// shellQuote quotes s as a single shell word. Single quotes pass UTF-8 through untouched, the only
+// character needing care is the single quote itself.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// shellQuoteAll quotes each of words, separating them with spaces
+func shellQuoteAll(words []string) string {
+	quoted := make([]string, len(words))
+	for i, w := range words {
+		quoted[i] = shellQuote(w)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// fishQuote quotes s as a single fish word, within single quotes fish only treats backslashes and
+// single quotes specially
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
+
+// shellCompletionCommand prints a completion script, for "prog completion bash"
+type shellCompletionCommand struct {
+	p *Program
+}
+
+const completionHelp = `
+Print a completion script for shell, one of bash, zsh or fish. Load it with:
+
+  bash  source <(%[1]s completion bash)
+  zsh   source <(%[1]s completion zsh)
+  fish  %[1]s completion fish | source
+`
+
+func (c *shellCompletionCommand) Name() string              { return "completion" }
+func (c *shellCompletionCommand) Args() string              { return "<shell>" }
+func (c *shellCompletionCommand) Desc() string              { return "Print a shell completion script" }
+func (c *shellCompletionCommand) Help() string              { return fmt.Sprintf(completionHelp, c.p.name) }
+func (c *shellCompletionCommand) Register(fs *flag.FlagSet) {}
+
+func (c *shellCompletionCommand) Run(ctx Context, args []string) error {
+	if len(args) != 1 {
+		return ErrShowUsage
+	}
+	return c.p.GenerateCompletion(args[0], ctx.Stdout())
+}
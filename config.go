@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// LoadConfig reads flag defaults from the file at path, decoded with unmarshal so the format is up
+// to the program, e.g. json.Unmarshal or a TOML or YAML package's. The file maps commands to flag
+// names to values, as with this JSON:
+//
+//	{"deploy": {"env": "staging", "jobs": 4}, "remote add": {"tag": ["a", "b"]}}
+//
+// Commands in a group are named by their path below the program, as in "remote add". Flags are
+// only set from the config when they weren't given on the command line or through an environment
+// variable bound with BindEnv. Values are checked like any other flag value, numbers are written
+// out in full and each value in a list sets the flag once, as repeating it on the command line
+// would.
+func (p *Program) LoadConfig(path string, unmarshal func([]byte, interface{}) error) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var config map[string]map[string]interface{}
+	if err := unmarshal(b, &config); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+
+	p.config = make(map[string]map[string][]string)
+	p.configPath = path
+	for cmd, flags := range config {
+		p.config[cmd] = make(map[string][]string)
+		for name, v := range flags {
+			values, err := configValues(v)
+			if err != nil {
+				return fmt.Errorf("%s: %s: -%s: %v", path, cmd, name, err)
+			}
+			p.config[cmd][name] = values
+		}
+	}
+	return nil
+}
+
+// configValues converts a decoded config value to the flag values it sets, one for each element of
+// a list
+func configValues(v interface{}) ([]string, error) {
+	list, ok := v.([]interface{})
+	if !ok {
+		list = []interface{}{v}
+	}
+
+	values := make([]string, 0, len(list))
+	for _, item := range list {
+		switch item := item.(type) {
+		case float64:
+			values = append(values, strconv.FormatFloat(item, 'f', -1, 64))
+		case float32:
+			values = append(values, strconv.FormatFloat(float64(item), 'f', -1, 32))
+		case nil, []interface{}, map[string]interface{}, map[interface{}]interface{}:
+			return nil, fmt.Errorf("unsupported value %v", item)
+		default:
+			values = append(values, fmt.Sprint(item))
+		}
+	}
+	return values, nil
+}
+
+// configKey returns the name cmd has in the config, its path below the program name
+func (p *Program) configKey(cmd Command) string {
+	if path := strings.TrimPrefix(p.commandPath(cmd), p.name+" "); path != p.name {
+		return path
+	}
+	return cmd.Name()
+}
+
+// applyConfig sets each flag of cmd in fs that hasn't been set yet from the loaded config
+func (p *Program) applyConfig(cmd Command, fs *flag.FlagSet) error {
+	key := p.configKey(cmd)
+	values := p.config[key]
+	if len(values) == 0 {
+		return nil
+	}
+
+	// a flag given by any one of its names counts as given for all of them
+	set := make(map[interface{}]bool)
+	fs.Visit(func(f *flag.Flag) {
+		set[flagKey(f)] = true
+	})
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		f := fs.Lookup(name)
+		if f == nil {
+			return fmt.Errorf("%s: %s: no such flag -%s", p.configPath, key, name)
+		}
+		if set[flagKey(f)] {
+			continue
+		}
+		for _, v := range values[name] {
+			if err := fs.Set(name, v); err != nil {
+				return fmt.Errorf("%s: %s: invalid value %q for -%s: %v", p.configPath, key, v, name, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Confirmer is implemented by commands that must be confirmed before they are run, such as
+// destructive operations. RequiresConfirmation returns the prompt shown to the user, an empty
+// prompt means no confirmation is needed.
+type Confirmer interface {
+	RequiresConfirmation() string
+}
+
+// ErrAborted is returned when the user declines to confirm a command.
+var ErrAborted = errors.New("aborted")
+
+// ErrConfirmationRequired is returned when a command requiring confirmation is run from a
+// non-interactive session without the -yes flag.
+var ErrConfirmationRequired = errors.New("confirmation required, use -yes to run non-interactively")
+
+// confirmationPrompt returns the confirmation prompt for cmd if it has one
+func confirmationPrompt(cmd Command) string {
+	if c, ok := cmd.(Confirmer); ok {
+		return strings.TrimSpace(c.RequiresConfirmation())
+	}
+	return ""
+}
+
+// IsInteractive reports whether stdin is attached to a terminal.
+func (e *Environment) IsInteractive() bool {
+	return isTerminal(e.stdin)
+}
+
+// Confirm asks the user a yes/no question on stderr and reads the answer from stdin. Anything
+// other than "y" or "yes" is treated as no.
+func (e *Environment) Confirm(prompt string) (bool, error) {
+	fmt.Fprintf(e.stderr, "%s [y/N] ", prompt)
+
+	answer, err := readLine(e.stdin)
+	if err != nil {
+		return false, err
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	}
+	return false, nil
+}
+
+// confirm prompts for confirmation, failing when it isn't possible to ask
+func (e *Environment) confirm(prompt string) error {
+	if !e.IsInteractive() {
+		return ErrConfirmationRequired
+	}
+
+	ok, err := e.Confirm(prompt)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrAborted
+	}
+	return nil
+}
+
+// readLine reads a single line from r a byte at a time, so nothing past the newline is consumed
+func readLine(r io.Reader) (string, error) {
+	var (
+		line strings.Builder
+		b    = make([]byte, 1)
+	)
+	for {
+		n, err := r.Read(b)
+		if n > 0 {
+			if b[0] == '\n' {
+				return line.String(), nil
+			}
+			line.WriteByte(b[0])
+		}
+		if err == io.EOF {
+			return line.String(), nil
+		} else if err != nil {
+			return line.String(), err
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// FlagConflicter is implemented by commands with flags that can't be used together. Each group
+// lists flags of which only one may be given on the command line, by any one of their names, e.g.
+// {{"json", "yaml"}} for output formats. Flags set from the environment, a config file or project
+// defaults don't count, only those given on the command line, which replace any project default
+// they conflict with.
+type FlagConflicter interface {
+	ConflictingFlags() [][]string
+}
+
+// checkConflicts returns an ErrConflictingFlags for the first group of cmd's conflicting flags with
+// more than one of them given, as found by givenFlags
+func (p *Program) checkConflicts(fs *flag.FlagSet, cmd Command, given map[interface{}]bool) error {
+	for _, keys := range conflictGroups(fs, cmd) {
+		var names []string
+		seen := make(map[interface{}]bool)
+		fs.Visit(func(f *flag.Flag) {
+			if key := flagKey(f); keys[key] && given[key] && !seen[key] {
+				seen[key] = true
+				names = append(names, "-"+f.Name)
+			}
+		})
+		if len(names) > 1 {
+			return &ErrConflictingFlags{
+				commandName: p.commandPath(cmd),
+				flags:       names,
+				usage:       p.createCommandUsage(fs, cmd, false),
+			}
+		}
+	}
+	return nil
+}
+
+// withoutConflicts returns the project defaults in args without the flags conflicting with one
+// given on the command line, so the command line wins
+func (p *Program) withoutConflicts(fs *flag.FlagSet, cmd Command, args []string, given map[interface{}]bool) []string {
+	drop := make(map[interface{}]bool)
+	for _, keys := range conflictGroups(fs, cmd) {
+		for key := range keys {
+			if given[key] {
+				for k := range keys {
+					drop[k] = !given[k]
+				}
+				break
+			}
+		}
+	}
+	if len(drop) == 0 {
+		return args
+	}
+
+	var kept []string
+	for i := 0; i < len(args); i++ {
+		name, _, hasValue := splitFlag(args[i])
+		f := fs.Lookup(name)
+		end := i + 1
+		if f != nil && !hasValue && !isBoolFlag(f) && end < len(args) {
+			end++
+		}
+		if f == nil || !drop[flagKey(f)] {
+			kept = append(kept, args[i:end]...)
+		}
+		i = end - 1
+	}
+	return kept
+}
+
+// conflictGroups returns the groups of cmd's conflicting flags as sets of flagKey
+func conflictGroups(fs *flag.FlagSet, cmd Command) []map[interface{}]bool {
+	c, ok := cmd.(FlagConflicter)
+	if !ok {
+		return nil
+	}
+
+	var groups []map[interface{}]bool
+	for _, group := range c.ConflictingFlags() {
+		keys := make(map[interface{}]bool)
+		for _, name := range group {
+			if f := fs.Lookup(name); f != nil {
+				keys[flagKey(f)] = true
+			}
+		}
+		groups = append(groups, keys)
+	}
+	return groups
+}
+
+// givenFlags returns the flags at the front of args, up to the first argument that isn't one of
+// fs's flags or a "--", keyed by flagKey
+func givenFlags(fs *flag.FlagSet, args []string) map[interface{}]bool {
+	given := make(map[interface{}]bool)
+	for i := 0; i < len(args); i++ {
+		name, _, hasValue := splitFlag(args[i])
+		f := fs.Lookup(name)
+		if f == nil {
+			break
+		}
+		given[flagKey(f)] = true
+		if !hasValue && !isBoolFlag(f) {
+			i++
+		}
+	}
+	return given
+}
+
+// ErrConflictingFlags is returned when flags a command declares as conflicting are given together,
+// followed by the command's usage:
+//
+//	prog export: flags -json and -yaml are mutually exclusive
+type ErrConflictingFlags struct {
+	commandName string
+	flags       []string
+	usage       string
+}
+
+// Error implements the error interface
+func (e *ErrConflictingFlags) Error() string {
+	return fmt.Sprintf("%s: %s\n\n%s", e.commandName, e.message(), strings.TrimRight(e.usage, "\n"))
+}
+
+// Flags returns the conflicting flags that were given, as they were named on the command line
+func (e *ErrConflictingFlags) Flags() []string {
+	return e.flags
+}
+
+// message describes the conflict, without the usage
+func (e *ErrConflictingFlags) message() string {
+	last := len(e.flags) - 1
+	return fmt.Sprintf("flags %s and %s are mutually exclusive", strings.Join(e.flags[:last], ", "), e.flags[last])
+}
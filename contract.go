@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"encoding/json"
+	"flag"
+	"strings"
+	"time"
+)
+
+// UsageContractVersion is the schema version of the document produced by UsageContract. Changes
+// within a major version only ever add fields, so consumers can rely on existing fields remaining.
+const UsageContractVersion = "1.1"
+
+type usageContract struct {
+	SchemaVersion string             `json:"schemaVersion"`
+	Name          string             `json:"name"`
+	Description   string             `json:"description,omitempty"`
+	Flags         []contractFlag     `json:"flags,omitempty"`
+	Default       *contractCommand   `json:"default,omitempty"`
+	Commands      []*contractCommand `json:"commands,omitempty"`
+}
+
+type contractCommand struct {
+	Name        string             `json:"name"`
+	Aliases     []string           `json:"aliases,omitempty"`
+	Args        string             `json:"args,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Help        string             `json:"help,omitempty"`
+	Flags       []contractFlag     `json:"flags,omitempty"`
+	Subcommands []*contractCommand `json:"subcommands,omitempty"`
+}
+
+type contractFlag struct {
+	Name     string   `json:"name"`
+	Short    string   `json:"short,omitempty"`
+	Type     string   `json:"type"`
+	Default  string   `json:"default"`
+	Usage    string   `json:"usage,omitempty"`
+	Required bool     `json:"required"`
+	Choices  []string `json:"choices,omitempty"`
+}
+
+// UsageContract describes the program's commands and flags as a versioned JSON document, for
+// systems that wrap or expose the program and need a stable description of its interface. Unlike
+// the usage text its layout is part of the package's API, see UsageContractVersion.
+//
+// The document holds the program's name, description and program wide flags, the default command
+// and every other command with its name, aliases, args, description, help, flags and, for groups,
+// subcommands described the same way. Each flag has its name, single letter short name when it has
+// one, type, default value, usage, whether it is required and any choices it is restricted to.
+func (p *Program) UsageContract() []byte {
+	c := &usageContract{
+		SchemaVersion: UsageContractVersion,
+		Name:          p.name,
+		Description:   strings.TrimSpace(p.desc),
+		Flags:         contractFlags(p.flags, nil),
+	}
+	if p.root != nil {
+		c.Default = p.contractCommand(p.root)
+	}
+	for _, cmd := range p.allCommands() {
+		c.Commands = append(c.Commands, p.contractCommand(cmd))
+	}
+
+	b, _ := json.MarshalIndent(c, "", "  ")
+	return b
+}
+
+func (p *Program) contractCommand(cmd Command) *contractCommand {
+	c := &contractCommand{
+		Name:        cmd.Name(),
+		Aliases:     commandAliases(cmd),
+		Args:        cmd.Args(),
+		Description: cmd.Desc(),
+		Help:        strings.TrimSpace(cmd.Help()),
+		Flags: contractFlags(p.scratchFlagSet(cmd), func(f *flag.Flag) bool {
+			return !p.isGlobalFlag(f)
+		}),
+	}
+	for _, sub := range subcommands(cmd) {
+		c.Subcommands = append(c.Subcommands, p.contractCommand(sub))
+	}
+	return c
+}
+
+// contractFlags describes the flags of fs, merging short and long names of the same flag
+func contractFlags(fs *flag.FlagSet, include func(*flag.Flag) bool) []contractFlag {
+	var flags []contractFlag
+	for _, group := range groupFlags(fs, false, include) {
+		f := group[len(group)-1]
+		cf := contractFlag{
+			Name:    f.Name,
+			Type:    flagType(f),
+			Default: f.DefValue,
+			Usage:   f.Usage,
+			Choices: flagChoices(f),
+		}
+		if len(group) > 1 {
+			cf.Name = ""
+			for _, g := range group {
+				if len(g.Name) > len(cf.Name) {
+					cf.Name = g.Name
+				}
+				if len(g.Name) == 1 && cf.Short == "" {
+					cf.Short = g.Name
+				}
+			}
+		}
+		flags = append(flags, cf)
+	}
+	return flags
+}
+
+// flagType names the type of value f holds
+func flagType(f *flag.Flag) string {
+	g, ok := f.Value.(flag.Getter)
+	if !ok {
+		return "value"
+	}
+
+	switch g.Get().(type) {
+	case bool:
+		return "bool"
+	case time.Duration:
+		return "duration"
+	case int, int64:
+		return "int"
+	case uint, uint64:
+		return "uint"
+	case float64:
+		return "float"
+	case string:
+		return "string"
+	}
+	return "value"
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"errors"
+	"strconv"
+)
+
+// CountValue is a flag.Value counting how many times a flag is given, for verbosity levels and the
+// like:
+//
+//	fs.Var(&c.verbosity, "v", "Increase verbosity")
+//	fs.Var(&c.verbosity, "verbose", "Increase verbosity")
+//
+// Each -v adds one and -verbose=3 sets the count outright. With WithPosixShortFlags -vvv counts as
+// three. The usage shows the count before parsing as the default.
+type CountValue int
+
+// IsBoolFlag lets the flag be given without a value
+func (c *CountValue) IsBoolFlag() bool { return true }
+
+func (c *CountValue) Get() interface{} { return int(*c) }
+func (c *CountValue) String() string   { return strconv.Itoa(int(*c)) }
+
+// Set adds one for the "true" the flag package gives a flag without a value, resets the count for
+// "false" and otherwise sets it to the number given
+func (c *CountValue) Set(s string) error {
+	switch s {
+	case "true":
+		*c++
+		return nil
+	case "false":
+		*c = 0
+		return nil
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return errors.New("invalid count")
+	}
+	*c = CountValue(n)
+	return nil
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// WithDebug adds the -debug flag, which traces each command run to stderr: the command with its
+// flags and args before it runs, then the error it returned and how long it took. With enabled set
+// tracing is on without the flag being given. The trace goes to the program's stderr, so tests can
+// capture it with WithStderr:
+//
+//	debug: run prog greet -p=true -pirate=true ["bob"]
+//	debug: prog greet returned <nil> after 1.2ms
+func WithDebug(enabled bool) Option {
+	return func(p *Program) {
+		p.flags.BoolVar(&p.debug, "debug", enabled, "Trace the commands run to stderr")
+	}
+}
+
+// debugf writes a line of the debug trace
+func (e *Environment) debugf(format string, v ...interface{}) {
+	fmt.Fprintf(e.stderr, "debug: "+format+"\n", v...)
+}
+
+// tracing wraps fn to trace the run of a command with its flags in fs
+func (p *Program) tracing(fs *flag.FlagSet, fn RunFunc) RunFunc {
+	return func(env *Environment, cmd Command, args []string) error {
+		var flags []string
+		fs.VisitAll(func(f *flag.Flag) {
+			flags = append(flags, fmt.Sprintf("-%s=%s", f.Name, f.Value))
+		})
+
+		path := p.commandPath(cmd)
+		env.debugf("run %s %s %q", path, strings.Join(flags, " "), args)
+		start := time.Now()
+		err := fn(env, cmd, args)
+		env.debugf("%s returned %v after %s", path, err, time.Since(start).Round(time.Microsecond))
+		return err
+	}
+}
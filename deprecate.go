@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// deprecation is where a deprecated command name forwards to
+type deprecation struct {
+	name    string // the command's new name
+	removal string // the version the old name is removed in, if known
+}
+
+// DeprecateCommand keeps old working as a name for the command renamed to new, warning each time it
+// is used that it is deprecated and, when removalVersion is given, which version removes it. Once
+// the program's version, set with WithVersion, reaches removalVersion using old is an error
+// instead. The old name is left out of the usage and a command actually called old takes
+// precedence.
+func (p *Program) DeprecateCommand(old, new, removalVersion string) {
+	if p.deprecated == nil {
+		p.deprecated = make(map[string]deprecation)
+	}
+	p.deprecated[old] = deprecation{name: new, removal: removalVersion}
+}
+
+// forwardDeprecated returns the command the deprecated name forwards to, if it is one
+func (p *Program) forwardDeprecated(name string) Command {
+	d, ok := p.deprecated[name]
+	if !ok {
+		return nil
+	}
+
+	for _, cmd := range p.allCommands() {
+		if cmd.Name() == d.name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// checkDeprecated warns when called is a deprecated name, or fails once it has been removed
+func (p *Program) checkDeprecated(called string, cmd Command) error {
+	d, ok := p.deprecated[called]
+	if !ok || hasName(cmd, called) {
+		return nil
+	}
+
+	switch {
+	case d.removal == "":
+		p.env.warn("%s is deprecated, use %s instead", called, d.name)
+	case p.version != "" && compareVersions(p.version, d.removal) >= 0:
+		return fmt.Errorf("%s: %s was removed in %s, use %s instead", p.name, called, d.removal, d.name)
+	default:
+		p.env.warn("%s is deprecated and will be removed in %s, use %s instead", called, d.removal, d.name)
+	}
+	return nil
+}
+
+// compareVersions compares two dotted versions such as "1.10.2" or "v2.0", returning -1, 0 or 1
+// when a is older, the same or newer than b. Missing parts count as 0 and anything after a "-" or
+// "+" is ignored.
+func compareVersions(a, b string) int {
+	pa, pb := versionParts(a), versionParts(b)
+	for len(pa) < len(pb) {
+		pa = append(pa, 0)
+	}
+	for len(pb) < len(pa) {
+		pb = append(pb, 0)
+	}
+
+	for i := range pa {
+		switch {
+		case pa[i] < pb[i]:
+			return -1
+		case pa[i] > pb[i]:
+			return 1
+		}
+	}
+	return 0
+}
+
+func versionParts(v string) []int {
+	v = strings.TrimPrefix(v, "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+
+	var parts []int
+	for _, s := range strings.Split(v, ".") {
+		n, _ := strconv.Atoi(s)
+		parts = append(parts, n)
+	}
+	return parts
+}
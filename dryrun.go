@@ -0,0 +1,17 @@
+package cmd
+
+import "flag"
+
+// WithDryRun adds the -dry-run flag to every command. The program doesn't enforce it, commands
+// check Context.DryRun and skip their changes, so destructive commands share one way of being
+// tried out. The flag is listed in the usage of each command.
+func WithDryRun() Option {
+	return func(p *Program) {
+		p.flags.BoolVar(&p.env.dryRun, "dry-run", false, "Show what would be done without doing it")
+	}
+}
+
+// isDryRunFlag checks whether f is the -dry-run flag added by WithDryRun
+func (p *Program) isDryRunFlag(f *flag.Flag) bool {
+	return f.Name == "dry-run" && p.isGlobalFlag(f)
+}
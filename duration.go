@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// DurationVar defines a duration flag which, unlike flag.DurationVar, accepts bare numbers as a
+// number of defaultUnit, so with a unit of time.Second "-timeout 30" is 30s and "-timeout 2m" is
+// still two minutes. def is the default value in the same form. The usage notes the default unit.
+func DurationVar(fs *flag.FlagSet, p *time.Duration, name string, defaultUnit time.Duration, def, usage string) {
+	v := &durationValue{p: p, unit: defaultUnit}
+	if err := v.Set(def); err != nil {
+		panic(fmt.Sprintf("cmd: invalid default %q for flag -%s: %v", def, name, err))
+	}
+	fs.Var(v, name, fmt.Sprintf("%s, in %s when no unit is given", usage, unitName(defaultUnit)))
+}
+
+// durationValue is a flag.Value for a duration whose unit defaults to unit
+type durationValue struct {
+	p    *time.Duration
+	unit time.Duration
+}
+
+func (d *durationValue) Get() interface{} { return *d.p }
+
+func (d *durationValue) String() string {
+	if d.p == nil {
+		return ""
+	}
+	return d.p.String()
+}
+
+func (d *durationValue) Set(s string) error {
+	if n, err := strconv.ParseFloat(s, 64); err == nil && !math.IsInf(n, 0) && !math.IsNaN(n) {
+		*d.p = time.Duration(n * float64(d.unit))
+		return nil
+	}
+
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return errors.New("invalid duration")
+	}
+	*d.p = v
+	return nil
+}
+
+// unitName returns the plural name of unit, e.g. "seconds"
+func unitName(unit time.Duration) string {
+	switch unit {
+	case time.Nanosecond:
+		return "nanoseconds"
+	case time.Microsecond:
+		return "microseconds"
+	case time.Millisecond:
+		return "milliseconds"
+	case time.Second:
+		return "seconds"
+	case time.Minute:
+		return "minutes"
+	case time.Hour:
+		return "hours"
+	}
+	return "units of " + unit.String()
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+)
+
+// DynamicDefault makes the default of the flag called name, already defined on fs, depend on the
+// environment the program runs in, e.g. -color defaulting to off in CI or -jobs to the number of
+// CPUs. It is called from Register, and fn is called with the program's environment before the
+// flags are parsed, so flags given on the command line still win. The usage marks the default as
+// "auto".
+func DynamicDefault(fs *flag.FlagSet, name string, fn func(*Environment) string) {
+	f := fs.Lookup(name)
+	if f == nil {
+		panic(fmt.Sprintf("cmd: DynamicDefault for undefined flag -%s", name))
+	}
+	f.Value = &dynamicDefault{Value: f.Value, fn: fn}
+}
+
+// dynamicDefault is a flag.Value whose default is worked out from the environment
+type dynamicDefault struct {
+	flag.Value
+	fn func(*Environment) string
+}
+
+func (d *dynamicDefault) IsBoolFlag() bool {
+	b, ok := d.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+func (d *dynamicDefault) Hidden() bool {
+	h, ok := d.Value.(interface{ Hidden() bool })
+	return ok && h.Hidden()
+}
+
+func (d *dynamicDefault) Get() interface{} {
+	if g, ok := d.Value.(flag.Getter); ok {
+		return g.Get()
+	}
+	return nil
+}
+
+// applyDynamicDefaults sets the flags of fs with dynamic defaults to the default for the
+// environment
+func (e *Environment) applyDynamicDefaults(fs *flag.FlagSet) {
+	fs.VisitAll(func(f *flag.Flag) {
+		d, ok := f.Value.(*dynamicDefault)
+		if !ok {
+			return
+		}
+		if err := d.Value.Set(d.fn(e)); err != nil {
+			e.warn("invalid default for -%s: %v", f.Name, err)
+			return
+		}
+		f.DefValue = d.Value.String()
+	})
+}
+
+// isDynamicDefault checks whether the default of f depends on the environment
+func isDynamicDefault(f *flag.Flag) bool {
+	_, ok := f.Value.(*dynamicDefault)
+	return ok
+}
+
+// unquoteUsage is flag.UnquoteUsage seeing through dynamic defaults, which would otherwise hide the
+// type of the flag they wrap
+func unquoteUsage(f *flag.Flag) (name, usage string) {
+	if d, ok := f.Value.(*dynamicDefault); ok {
+		inner := *f
+		inner.Value = d.Value
+		f = &inner
+	}
+	return flag.UnquoteUsage(f)
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// getenv returns the value of key in the environment, the last definition wins as with os.Getenv
+func (e *Environment) getenv(key string) string {
+	v, _ := e.lookupEnv(key)
+	return v
+}
+
+// lookupEnv returns the value of key in the environment and whether it is set
+func (e *Environment) lookupEnv(key string) (string, bool) {
+	for i := len(e.Env) - 1; i >= 0; i-- {
+		if k, v, ok := strings.Cut(e.Env[i], "="); ok && k == key {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// envPrefix returns the prefix used for the program's environment variables, the program name upper
+// cased with anything other than letters and digits replaced by underscores, e.g. "GREET_"
+func (p *Program) envPrefix() string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, p.name) + "_"
+}
+
+// snapshotMode checks whether usage should be rendered deterministically for snapshot tests
+func (p *Program) snapshotMode() bool {
+	return p.snapshot || p.env.getenv(p.envPrefix()+"SNAPSHOT") == "1"
+}
+
+// BindEnv makes flags fall back to environment variables named prefix followed by the upper cased
+// flag name, with dashes replaced by underscores, so with a prefix of "MYTOOL_" the -dry-run flag
+// defaults from MYTOOL_DRY_RUN. An empty prefix uses the program's own, the upper cased program
+// name followed by an underscore. Flags given on the command line take precedence, and variables
+// are read from Environment.Env so tests can give a fake environment. The usage names the variable
+// after each flag's default, as in "(default: <none>, env: MYTOOL_TOKEN)".
+func (p *Program) BindEnv(prefix string) {
+	if prefix == "" {
+		prefix = p.envPrefix()
+	}
+	p.envBinding = prefix
+}
+
+// envKey returns the environment variable bound to the flag called name
+func (p *Program) envKey(name string) string {
+	return p.envBinding + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// longestName returns the longest name of a group of flags, the one named in the usage for their
+// environment variable
+func longestName(group []*flag.Flag) string {
+	var name string
+	for _, f := range group {
+		if len(f.Name) > len(name) {
+			name = f.Name
+		}
+	}
+	return name
+}
+
+// applyEnv sets each flag in fs that wasn't given on the command line from its environment
+// variable, if it is set. Values are checked against the flag's type so a malformed variable is
+// reported by name rather than as a confusing parse error.
+func (p *Program) applyEnv(fs *flag.FlagSet) error {
+	if p.envBinding == "" {
+		return nil
+	}
+
+	// a flag given by any one of its names counts as given for all of them
+	set := make(map[interface{}]bool)
+	fs.Visit(func(f *flag.Flag) {
+		set[flagKey(f)] = true
+	})
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if err != nil || set[flagKey(f)] {
+			return
+		}
+
+		key := p.envKey(f.Name)
+		v, ok := p.env.lookupEnv(key)
+		if !ok {
+			return
+		}
+		if serr := fs.Set(f.Name, v); serr != nil {
+			err = fmt.Errorf("invalid value for %s (%s): %s", key, flagType(f), v)
+		}
+	})
+	return err
+}
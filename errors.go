@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// usageError is returned when the program usage has been requested
+type usageError struct {
+	usage string
+}
+
+// Error implements the error interface
+func (e *usageError) Error() string {
+	return e.usage
+}
+
+// Is reports whether target is ErrHelpRequested
+func (e *usageError) Is(target error) bool {
+	return target == ErrHelpRequested
+}
+
+// FormatError formats err for display to the user, followed by an indented "hint:" line when it
+// wraps a HintError. The program usage asked for with "prog help" formats as the usage, which
+// belongs on stdout like the rest of the help, and other errors matching ErrHelpRequested as an
+// empty string, their help has been printed. With -porcelain set errors are rendered as a single
+// tab separated line of the form "error\tCODE\tmessage".
+func (p *Program) FormatError(err error) string {
+	var ue *usageError
+	if errors.As(err, &ue) {
+		return ue.usage
+	}
+	if errors.Is(err, ErrHelpRequested) {
+		return ""
+	}
+
+	if !p.porcelain {
+		var he *HintError
+		if errors.As(err, &he) && he.Hint != "" {
+			return fmt.Sprintf("%s\n  hint: %s", err.Error(), he.Hint)
+		}
+		return err.Error()
+	}
+
+	msg := err.Error()
+	var (
+		nde *ErrNoDefaultCommand
+		cf  *ErrConflictingFlags
+	)
+	switch {
+	case errors.As(err, &nde):
+		msg = "no command given"
+	case errors.As(err, &cf):
+		msg = cf.commandName + ": " + cf.message()
+	}
+	return fmt.Sprintf("error\t%s\t%s", errorCode(err), strings.Join(strings.Fields(msg), " "))
+}
+
+// errorCode returns the stable identifier used for err in porcelain output
+func errorCode(err error) string {
+	var (
+		nsc *ErrNoSuchCommand
+		nde *ErrNoDefaultCommand
+		amb *ErrAmbiguousCommand
+		pre *ErrPreconditionFailed
+		ia  *ErrInvalidArgs
+		cf  *ErrConflictingFlags
+	)
+	switch {
+	case errors.Is(err, ErrParseArgs):
+		return "PARSE_ARGS"
+	case errors.As(err, &ia):
+		return "INVALID_ARGS"
+	case errors.As(err, &cf):
+		return "CONFLICTING_FLAGS"
+	case errors.Is(err, ErrAborted):
+		return "ABORTED"
+	case errors.Is(err, ErrConfirmationRequired):
+		return "CONFIRMATION_REQUIRED"
+	case errors.As(err, &nsc):
+		return "NO_SUCH_COMMAND"
+	case errors.As(err, &nde):
+		return "NO_DEFAULT_COMMAND"
+	case errors.As(err, &amb):
+		return "AMBIGUOUS_COMMAND"
+	case errors.As(err, &pre):
+		return "PRECONDITION_FAILED"
+	}
+	return "ERROR"
+}
+
+// writePorcelainCommands lists each command as "name\tdesc", one per line
+func (p *Program) writePorcelainCommands(w io.Writer) {
+	if p.root != nil {
+		fmt.Fprintf(w, "%s\t%s\n", p.root.Name(), p.root.Desc())
+	}
+	for _, cmd := range p.allCommands() {
+		fmt.Fprintf(w, "%s\t%s\n", cmd.Name(), cmd.Desc())
+	}
+}
+
+// HintError is an error with a suggestion of how to fix it, which FormatError shows on a line of
+// its own below the error:
+//
+//	deploy: no credentials found
+//	  hint: run "prog login" first
+//
+// Commands return it from Run as &HintError{Err: err, Hint: "..."}.
+type HintError struct {
+	Err  error
+	Hint string
+}
+
+// Error implements the error interface, it is the message of the underlying error without the hint
+func (e *HintError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *HintError) Unwrap() error {
+	return e.Err
+}
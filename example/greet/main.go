@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bufio"
 	"flag"
 	"fmt"
 	"os"
@@ -10,18 +11,16 @@ import (
 )
 
 func main() {
-	p, err := cmd.NewProgram("greet", "", &greetCommand{}, nil)
+	p, err := cmd.NewProgram("greet", "", &greetCommand{}, nil, cmd.WithJSONOutput(), cmd.WithInterspersedFlags(true))
 	if err != nil {
 		cmd.Err.Fatal(err)
 	}
-	if err := p.Run(os.Args, func(env *cmd.Environment, c cmd.Command, args []string) error {
+	os.Exit(p.Main(os.Args, func(env *cmd.Environment, c cmd.Command, args []string) error {
 		if err := c.Run(env.GetDefaultContext(), args); err != nil {
-			return fmt.Errorf("%s: %v", c.Name(), err)
+			return fmt.Errorf("%s: %w", c.Name(), err)
 		}
 		return nil
-	}); err != nil {
-		cmd.Err.Fatal(err)
-	}
+	}))
 }
 
 type greetCommand struct {
@@ -36,6 +35,11 @@ func (c *greetCommand) Name() string { return "greet" }
 func (c *greetCommand) Args() string { return "[name]" }
 func (c *greetCommand) Desc() string { return "says hello" }
 func (c *greetCommand) Help() string { return strings.TrimSpace(greetHelp) }
+func (c *greetCommand) Examples() []cmd.Example {
+	return []cmd.Example{
+		{Desc: "Greet Bob like a pirate", Command: "greet -pirate Bob"},
+	}
+}
 func (c *greetCommand) Register(fs *flag.FlagSet) {
 	fs.BoolVar(&c.pirate, "pirate", false, "Say hello like a pirate")
 	fs.BoolVar(&c.pirate, "p", false, "Say hello like a pirate")
@@ -48,12 +52,31 @@ func (c *greetCommand) Run(ctx cmd.Context, args []string) error {
 		greeting = "Ahoy, %s!"
 	}
 
-	switch len(args) {
-	case 0:
-		cmd.Out.Printf(greeting, "there")
+	switch {
+	case len(args) > 0:
+		return greet(ctx, greeting, args[0])
+	case cmd.StdinPiped(ctx):
+		// greet each name piped in, one per line
+		sc := bufio.NewScanner(ctx.Stdin())
+		for sc.Scan() {
+			if name := strings.TrimSpace(sc.Text()); name != "" {
+				if err := greet(ctx, greeting, name); err != nil {
+					return err
+				}
+			}
+		}
+		return sc.Err()
 	default:
-		cmd.Out.Printf(greeting, args[0])
+		return greet(ctx, greeting, "there")
 	}
+}
 
+// greet prints the greeting for name, as {"greeting":"..."} with -json
+func greet(ctx cmd.Context, greeting, name string) error {
+	msg := fmt.Sprintf(greeting, name)
+	if ctx.OutputFormat() == "json" {
+		return ctx.Encode(map[string]string{"greeting": msg})
+	}
+	ctx.OutLog().Print(msg)
 	return nil
 }
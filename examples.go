@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+)
+
+// Example is a way of calling a command, shown in its usage.
+type Example struct {
+	Desc    string // what the example does, e.g. "Greet Bob like a pirate"
+	Command string // the command line, e.g. "greet -pirate Bob"
+}
+
+// Exampler is implemented by commands with examples of how to call them, listed under "Examples"
+// after the flags in their usage.
+type Exampler interface {
+	Examples() []Example
+}
+
+// commandExamples returns the examples of cmd, nil when it doesn't implement Exampler
+func commandExamples(cmd Command) []Example {
+	if e, ok := cmd.(Exampler); ok {
+		return e.Examples()
+	}
+	return nil
+}
+
+// writeExamples writes examples to w, each command below a comment with its description
+func (p *Program) writeExamples(w io.Writer, examples []Example) {
+	for i, ex := range examples {
+		if i > 0 {
+			fmt.Fprintln(w, "")
+		}
+		if ex.Desc != "" {
+			fmt.Fprintf(w, "%s# %s\n", p.style.Indent, ex.Desc)
+		}
+		fmt.Fprintf(w, "%s%s\n", p.style.Indent, ex.Command)
+	}
+}
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ExitCoder is implemented by errors that carry the process exit code to use.
+type ExitCoder interface {
+	ExitCode() int
+}
+
+// SetExitCodeMap sets the exit codes used for particular errors, matched with errors.Is. It lets a
+// program keep its exit code policy in one place rather than having each error implement
+// ExitCoder. When an error matches several entries, such as a wrapper and the sentinel it wraps,
+// the entry matching closest to the error returned wins, here the wrapper, and entries matching the
+// same error give the lowest of their codes.
+func (p *Program) SetExitCodeMap(codes map[error]int) {
+	p.exitCodes = codes
+}
+
+// ExitCode returns the process exit code for an error returned by Run. An error implementing
+// ExitCoder takes precedence, then the exit code map, then the defaults: 0 for no error or a
+// request for help, 2 for usage errors and 1 for anything else.
+func (p *Program) ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	var ec ExitCoder
+	if errors.As(err, &ec) {
+		return ec.ExitCode()
+	}
+
+	if code, ok := p.mappedExitCode(err); ok {
+		return code
+	}
+
+	var (
+		ue  *usageError
+		nsc *ErrNoSuchCommand
+		nde *ErrNoDefaultCommand
+		nsf *ErrNoSuchFlag
+		amb *ErrAmbiguousCommand
+		ia  *ErrInvalidArgs
+		cf  *ErrConflictingFlags
+	)
+	switch {
+	case errors.As(err, &ue), errors.Is(err, ErrHelpRequested):
+		return 0
+	case errors.Is(err, ErrParseArgs), errors.As(err, &ia), errors.As(err, &cf), errors.As(err, &nsc), errors.As(err, &nde), errors.As(err, &nsf), errors.As(err, &amb):
+		return 2
+	}
+	return 1
+}
+
+// mappedExitCode returns the code of the exit code map entry matching err, in the order given by
+// SetExitCodeMap
+func (p *Program) mappedExitCode(err error) (int, bool) {
+	if len(p.exitCodes) == 0 {
+		return 0, false
+	}
+
+	targets := make([]error, 0, len(p.exitCodes))
+	for target := range p.exitCodes {
+		targets = append(targets, target)
+	}
+	sort.SliceStable(targets, func(i, j int) bool {
+		return p.exitCodes[targets[i]] < p.exitCodes[targets[j]]
+	})
+
+	var (
+		code  int
+		found bool
+	)
+	walkErrors(err, func(e error) bool {
+		for _, target := range targets {
+			if isError(e, target) {
+				code, found = p.exitCodes[target], true
+				return false
+			}
+		}
+		return true
+	})
+	return code, found
+}
+
+// walkErrors calls fn with err and then each error it wraps, depth first as errors.Is goes through
+// them, until fn returns false
+func walkErrors(err error, fn func(error) bool) bool {
+	if err == nil {
+		return true
+	}
+	if !fn(err) {
+		return false
+	}
+
+	switch u := err.(type) {
+	case interface{ Unwrap() error }:
+		return walkErrors(u.Unwrap(), fn)
+	case interface{ Unwrap() []error }:
+		for _, e := range u.Unwrap() {
+			if !walkErrors(e, fn) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// isError reports whether err itself is target, as errors.Is checks each error it goes through
+func isError(err, target error) bool {
+	if target == nil {
+		return false
+	}
+	if reflect.TypeOf(target).Comparable() && err == target {
+		return true
+	}
+	x, ok := err.(interface{ Is(error) bool })
+	return ok && x.Is(target)
+}
+
+// Main runs the program with args and fn, prints any error to the program's stderr with
+// FormatError, or the program usage to stdout when it was asked for, and returns the exit code for
+// it, so main can be:
+//
+//	os.Exit(p.Main(os.Args, fn))
+func (p *Program) Main(args []string, fn RunFunc) int {
+	err := p.Run(args, fn)
+	if err != nil {
+		var ue *usageError
+		out := p.Err
+		if errors.As(err, &ue) {
+			out = p.Out
+		}
+		if msg := p.FormatError(err); msg != "" {
+			out.Print(strings.TrimSuffix(msg, "\n"))
+		}
+	}
+	return p.ExitCode(err)
+}
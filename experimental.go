@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+)
+
+// ExperimentalFlag defines a boolean flag for an experimental feature. When enabled is false, which
+// is typically decided by an environment variable such as PROG_EXPERIMENTAL=1, the flag is hidden
+// from usage and giving it is an error.
+func ExperimentalFlag(fs *flag.FlagSet, name, usage string, enabled bool) *bool {
+	f := &experimentalFlag{enabled: enabled}
+	fs.Var(f, name, usage)
+	return &f.value
+}
+
+// experimentalFlag is a boolean flag.Value that rejects values unless enabled
+type experimentalFlag struct {
+	enabled bool
+	value   bool
+}
+
+func (f *experimentalFlag) String() string   { return strconv.FormatBool(f.value) }
+func (f *experimentalFlag) IsBoolFlag() bool { return true }
+func (f *experimentalFlag) Hidden() bool     { return !f.enabled }
+
+func (f *experimentalFlag) Set(s string) error {
+	if !f.enabled {
+		return fmt.Errorf("experimental flag is not enabled")
+	}
+
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	f.value = v
+	return nil
+}
+
+// isHiddenFlag checks whether f should be left out of usage and completion
+func isHiddenFlag(f *flag.Flag) bool {
+	h, ok := f.Value.(interface{ Hidden() bool })
+	return ok && h.Hidden()
+}
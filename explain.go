@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// explainCommand writes what running cmd with the flags parsed into fs would do, for -explain. The
+// command is named by its full path, e.g. "prog remote add" for a command in a group:
+//
+//	Command: prog deploy
+//	Working directory: /home/user/site
+//	Arguments: "production"
+//
+//	Flags:
+//
+//	  -f -force  true
+//	  -timeout   0s (default)
+func (p *Program) explainCommand(w io.Writer, fs *flag.FlagSet, cmd Command) error {
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	args := make([]string, len(fs.Args()))
+	for i, arg := range fs.Args() {
+		args[i] = strconv.Quote(arg)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Command: %s\n", p.commandPath(cmd))
+	fmt.Fprintf(&b, "Working directory: %s\n", p.env.WorkingDir)
+	fmt.Fprintf(&b, "Arguments: %s\n", strings.Join(args, " "))
+
+	groups := groupFlags(fs, true, func(f *flag.Flag) bool {
+		return !p.isGlobalFlag(f)
+	})
+	if len(groups) > 0 {
+		fmt.Fprintln(&b, "")
+		fmt.Fprintln(&b, p.style.Heading("Flags"))
+		fmt.Fprintln(&b, "")
+
+		t := p.usageTable()
+		for _, group := range groups {
+			names := make([]string, len(group))
+			given := false
+			for i, f := range group {
+				names[i] = "-" + f.Name
+				given = given || set[f.Name]
+			}
+
+			value := prettyDefaultValue(group[0].Value.String())
+			if !given {
+				value += " (default)"
+			}
+			t.row(p.style.Indent+strings.Join(names, " "), value)
+		}
+		t.write(&b)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// OpenArg opens the file named by a path argument for reading. Relative paths are resolved against
+// the context's working directory and "-" reads from stdin, closing it leaves stdin open.
+func OpenArg(ctx Context, path string) (io.ReadCloser, error) {
+	if path == "-" {
+		return io.NopCloser(ctx.Stdin()), nil
+	}
+
+	f, err := os.Open(resolvePath(ctx, path))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%s: no such file", path)
+	} else if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// CreateArg creates or truncates the file named by a path argument for writing. Relative paths
+// are resolved against the context's working directory and "-" writes to stdout, closing it leaves
+// stdout open.
+func CreateArg(ctx Context, path string) (io.WriteCloser, error) {
+	if path == "-" {
+		return nopWriteCloser{ctx.Stdout()}, nil
+	}
+
+	f, err := os.Create(resolvePath(ctx, path))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%s: no such directory", filepath.Dir(path))
+	} else if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// resolvePath makes path absolute relative to the context's working directory
+func resolvePath(ctx Context, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(ctx.WorkingDir(), path)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
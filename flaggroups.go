@@ -0,0 +1,36 @@
+package cmd
+
+import "flag"
+
+// FlagGroup is a titled section of a command's flags in its usage.
+type FlagGroup struct {
+	Title string   // the heading of the section, e.g. "Output options"
+	Flags []string // the names of the flags in the section, any one name of a flag will do
+}
+
+// FlagGrouper is implemented by commands with enough flags to want them in sections in their
+// usage. Flags left out of every group are listed under "Flags" after the groups.
+type FlagGrouper interface {
+	FlagGroups() []FlagGroup
+}
+
+// flagGroups returns the flag groups of cmd, nil when it doesn't implement FlagGrouper
+func flagGroups(cmd Command) []FlagGroup {
+	if g, ok := cmd.(FlagGrouper); ok {
+		return g.FlagGroups()
+	}
+	return nil
+}
+
+// inFlagGroup returns a func accepting the flags of fs named in g, under whichever of their names
+func inFlagGroup(fs *flag.FlagSet, g FlagGroup) func(*flag.Flag) bool {
+	keys := make(map[interface{}]bool)
+	for _, name := range g.Flags {
+		if f := fs.Lookup(name); f != nil {
+			keys[flagKey(f)] = true
+		}
+	}
+	return func(f *flag.Flag) bool {
+		return keys[flagKey(f)]
+	}
+}
@@ -0,0 +1,261 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// builtinFlags holds the values of the flags the program adds to commands on top of their own
+type builtinFlags struct {
+	yes     bool
+	timeout time.Duration
+	output  *choiceValue
+}
+
+// commandFlagSet creates the FlagSet for cmd, holding its own flags, the program wide flags and
+// any flags the program adds for the optional interfaces cmd implements.
+func (p *Program) commandFlagSet(cmd Command, output io.Writer) (*flag.FlagSet, *builtinFlags) {
+	fs := flag.NewFlagSet(cmd.Name(), flag.ContinueOnError)
+	fs.SetOutput(output)
+	cmd.Register(fs)
+	p.env.applyDynamicDefaults(fs)
+	p.registerGlobalFlags(fs)
+
+	builtin := &builtinFlags{}
+	if confirmationPrompt(cmd) != "" && fs.Lookup("yes") == nil {
+		fs.BoolVar(&builtin.yes, "yes", false, "Run without asking for confirmation")
+	}
+
+	if timeout, ok := p.defaultTimeout(cmd); ok && fs.Lookup("timeout") == nil {
+		fs.DurationVar(&builtin.timeout, "timeout", timeout, "Maximum time to run for, 0 for no limit")
+	}
+
+	if formats := outputFormats(cmd); len(formats) > 0 && fs.Lookup("output") == nil {
+		builtin.output = newChoiceValue(formats)
+		usage := "Output format, one of " + strings.Join(formats, ", ")
+		fs.Var(builtin.output, "output", usage)
+		if fs.Lookup("o") == nil {
+			fs.Var(builtin.output, "o", usage)
+		}
+	}
+
+	return fs, builtin
+}
+
+// scratchFlagSet returns a FlagSet with every flag cmd accepts, for inspection rather than parsing
+func (p *Program) scratchFlagSet(cmd Command) *flag.FlagSet {
+	fs, _ := p.commandFlagSet(cmd, io.Discard)
+	return fs
+}
+
+// lookupCommand finds the command called name or one of its aliases, the root command is found by
+// its name or by an empty name.
+func (p *Program) lookupCommand(name string) (Command, error) {
+	if cmd, err := p.findCommand(name); cmd != nil || err != nil {
+		return cmd, err
+	}
+	if p.root != nil && (name == "" || name == p.root.Name()) {
+		return p.root, nil
+	}
+	return nil, p.noSuchCommand(name)
+}
+
+// FlagHelp returns the usage line for a single flag of a command, as it appears in the command's
+// help, e.g. "-p -pirate  Say hello like a pirate (default: false)". It is useful for pointing at
+// the documentation of a flag given a bad value.
+func (p *Program) FlagHelp(cmdName, flagName string) (string, error) {
+	cmd, err := p.lookupCommand(cmdName)
+	if err != nil {
+		return "", err
+	}
+
+	fs := p.scratchFlagSet(cmd)
+	target := fs.Lookup(strings.TrimLeft(flagName, "-"))
+	if target == nil {
+		return "", &ErrNoSuchFlag{
+			commandName: cmd.Name(),
+			flagName:    flagName,
+		}
+	}
+
+	line := p.flagUsage(fs, false, func(f *flag.Flag) bool {
+		return f.Name == target.Name || flagKey(f) == flagKey(target)
+	})
+	return strings.TrimSpace(line), nil
+}
+
+// ErrNoSuchFlag is returned when the requested flag is not found
+type ErrNoSuchFlag struct {
+	commandName string
+	flagName    string
+}
+
+// Error implements the error interface
+func (e *ErrNoSuchFlag) Error() string {
+	return fmt.Sprintf("%s: %s: no such flag", e.commandName, e.flagName)
+}
+
+// parseGlobalFlags consumes the program wide flags given before the command name, returning the
+// remaining args with the program name still in place. Flag values are only set when apply is true.
+func (p *Program) parseGlobalFlags(args []string, apply bool) ([]string, error) {
+	i := 1
+	for i < len(args) {
+		name, value, hasValue := splitFlag(args[i])
+		if name == "" {
+			break
+		}
+		f := p.flags.Lookup(name)
+		if f == nil {
+			break
+		}
+
+		if !hasValue {
+			if isBoolFlag(f) {
+				value = "true"
+			} else if i+1 < len(args) {
+				i++
+				value = args[i]
+			} else {
+				return nil, &ErrParseFlags{
+					commandName: p.name,
+					cause:       fmt.Errorf("flag needs an argument: -%s", name),
+				}
+			}
+		}
+		if apply {
+			if err := p.flags.Set(name, value); err != nil {
+				return nil, &ErrParseFlags{
+					commandName: p.name,
+					cause:       fmt.Errorf("invalid value %q for flag -%s: %v", value, name, err),
+				}
+			}
+		}
+		i++
+	}
+
+	if i == 1 {
+		return args, nil
+	}
+	return append([]string{args[0]}, args[i:]...), nil
+}
+
+// PersistentFlags registers flags that apply to every command, such as -verbose or -config, by
+// calling fn with the program wide FlagSet. They can be given before or after the command name and
+// are listed in the program usage. Commands read them like their own, through the variables fn
+// binds them to or Context.Flags.
+func (p *Program) PersistentFlags(fn func(*flag.FlagSet)) {
+	fn(p.flags)
+}
+
+// resetGlobalFlags puts the program wide flags back to their defaults, so flags given to one run
+// of the program don't carry over into the next
+func (p *Program) resetGlobalFlags() {
+	p.flags.VisitAll(func(f *flag.Flag) {
+		if f.Value.String() == f.DefValue {
+			return
+		}
+		if r, ok := f.Value.(interface{ reset(def string) error }); ok {
+			r.reset(f.DefValue)
+			return
+		}
+		f.Value.Set(f.DefValue)
+	})
+}
+
+// withFlags records the parsed flags of the running command in the environment until the returned
+// func is called
+func (e *Environment) withFlags(fs *flag.FlagSet) func() {
+	prev := e.flags
+	e.flags = fs
+	return func() {
+		e.flags = prev
+	}
+}
+
+// ErrParseFlags is returned when flags can't be parsed, such as an unknown flag or a bad value. It
+// matches ErrParseArgs with errors.Is and unwraps to the error from the flag package:
+//
+//	prog deploy: flag provided but not defined: -x
+type ErrParseFlags struct {
+	commandName string
+	cause       error
+}
+
+// Error implements the error interface
+func (e *ErrParseFlags) Error() string {
+	return fmt.Sprintf("%s: %v", e.commandName, e.cause)
+}
+
+// Unwrap returns the reason the flags couldn't be parsed
+func (e *ErrParseFlags) Unwrap() error {
+	return e.cause
+}
+
+// Is reports whether target is ErrParseArgs
+func (e *ErrParseFlags) Is(target error) bool {
+	return target == ErrParseArgs
+}
+
+// Cause returns the reason the flags couldn't be parsed
+func (e *ErrParseFlags) Cause() error {
+	return e.cause
+}
+
+// registerGlobalFlags adds the program wide flags to fs so they can also be given after the
+// command name. Flags the command registers itself take precedence.
+func (p *Program) registerGlobalFlags(fs *flag.FlagSet) {
+	p.flags.VisitAll(func(f *flag.Flag) {
+		if fs.Lookup(f.Name) == nil {
+			fs.Var(f.Value, f.Name, f.Usage)
+		}
+	})
+}
+
+// isGlobalFlag checks whether f was registered by registerGlobalFlags
+func (p *Program) isGlobalFlag(f *flag.Flag) bool {
+	gf := p.flags.Lookup(f.Name)
+	return gf != nil && gf.Value == f.Value
+}
+
+// splitFlag splits an argument of the form -name or -name=value, name is empty if arg isn't a flag
+func splitFlag(arg string) (name, value string, hasValue bool) {
+	if len(arg) < 2 || arg[0] != '-' || arg == "--" {
+		return "", "", false
+	}
+
+	name = strings.TrimPrefix(arg[1:], "-")
+	if i := strings.Index(name, "="); i >= 0 {
+		return name[:i], name[i+1:], true
+	}
+	return name, "", false
+}
+
+// isBoolFlag checks whether f can be given without a value
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// ParseFlags registers the flags of c on a new FlagSet and parses args with it, returning the
+// FlagSet and the args left after the flags. It lets a command's Register be tested on its own,
+// without a Program:
+//
+//	fs, args, err := cmd.ParseFlags(&greetCommand{}, []string{"-p", "world"})
+//	if err != nil || fs.Lookup("pirate").Value.String() != "true" || args[0] != "world" {
+//		t.Fatalf("-p didn't set -pirate: %v", err)
+//	}
+//
+// Only the command's own flags are registered, not those the program adds.
+func ParseFlags(c Command, args []string) (*flag.FlagSet, []string, error) {
+	fs := flag.NewFlagSet(c.Name(), flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	c.Register(fs)
+
+	if err := fs.Parse(args); err != nil {
+		return fs, nil, err
+	}
+	return fs, fs.Args(), nil
+}
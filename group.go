@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"flag"
+	"reflect"
+	"strings"
+)
+
+// CommandGroup is a command holding subcommands, for tools such as "prog remote add" where remote
+// groups the commands for working with remotes. Groups can be nested, and run on their own they
+// print their usage listing their subcommands.
+type CommandGroup struct {
+	name     string
+	desc     string
+	commands []Command
+}
+
+// NewCommandGroup returns a group called name holding cmds.
+func NewCommandGroup(name, desc string, cmds ...Command) *CommandGroup {
+	return &CommandGroup{name: name, desc: desc, commands: cmds}
+}
+
+func (g *CommandGroup) Name() string              { return g.name }
+func (g *CommandGroup) Args() string              { return "<command>" }
+func (g *CommandGroup) Desc() string              { return g.desc }
+func (g *CommandGroup) Help() string              { return g.desc }
+func (g *CommandGroup) Register(fs *flag.FlagSet) {}
+func (g *CommandGroup) Commands() []Command       { return g.commands }
+func (g *CommandGroup) GroupOnly()                {}
+
+// Run prints the usage of the group, a group does nothing on its own
+func (g *CommandGroup) Run(ctx Context, args []string) error {
+	return ErrShowUsage
+}
+
+// Grouper is implemented by commands holding subcommands, such as CommandGroup. The subcommands are
+// called by name after the command's own.
+type Grouper interface {
+	Commands() []Command
+}
+
+// GroupOnly is implemented by groups that have nothing to do on their own. Run without a
+// subcommand they print their usage, listing their subcommands, and the program's RunFunc isn't
+// called.
+type GroupOnly interface {
+	Grouper
+	GroupOnly()
+}
+
+// subcommands returns the commands cmd groups, if it is a group
+func subcommands(cmd Command) []Command {
+	if g, ok := cmd.(Grouper); ok {
+		return g.Commands()
+	}
+	return nil
+}
+
+// isGroupOnly checks whether cmd only groups other commands
+func isGroupOnly(cmd Command) bool {
+	_, ok := cmd.(GroupOnly)
+	return ok
+}
+
+// descend follows args down from cmd through its subcommands, returning the command they name and
+// the args that follow it. It stops at the first flag, so flags go after the full command path.
+func (p *Program) descend(cmd Command, args []string) (Command, []string, error) {
+	for len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		children := subcommands(cmd)
+		if children == nil {
+			break
+		}
+
+		var child Command
+		for _, c := range children {
+			if hasName(c, args[0]) || p.foldCase && hasNameFold(c, args[0]) {
+				child = c
+				break
+			}
+		}
+		if child != nil {
+			cmd, args = child, args[1:]
+			continue
+		}
+
+		switch matches := patternMatches(children, args[0]); len(matches) {
+		case 0:
+			return nil, nil, &ErrNoSuchCommand{
+				programName: p.commandPath(cmd),
+				commandName: args[0],
+			}
+		case 1:
+			// the matched arg is left for the command
+			return matches[0], args, nil
+		default:
+			return nil, nil, &ErrAmbiguousCommand{
+				programName: p.commandPath(cmd),
+				commandName: args[0],
+				candidates:  matches,
+			}
+		}
+	}
+	return cmd, args, nil
+}
+
+// groupPath returns the names leading to cmd through the groups in cmds, or nil if it isn't found
+func groupPath(cmds []Command, cmd Command) []string {
+	for _, c := range cmds {
+		if sameCommand(c, cmd) {
+			return []string{c.Name()}
+		}
+		if path := groupPath(subcommands(c), cmd); path != nil {
+			return append([]string{c.Name()}, path...)
+		}
+	}
+	return nil
+}
+
+// parentGroups returns the groups cmd is in, outermost first, e.g. remote for "prog remote add"
+func (p *Program) parentGroups(cmd Command) []Command {
+	var groups []Command
+	cmds := p.allCommands()
+	path := groupPath(cmds, cmd)
+	for i := 0; i < len(path)-1; i++ {
+		for _, c := range cmds {
+			if c.Name() == path[i] {
+				groups = append(groups, c)
+				cmds = subcommands(c)
+				break
+			}
+		}
+	}
+	return groups
+}
+
+// sameCommand checks whether a and b are the same command, by identity where the commands can be
+// compared and by name otherwise
+func sameCommand(a, b Command) bool {
+	ta, tb := reflect.TypeOf(a), reflect.TypeOf(b)
+	if ta != tb {
+		return false
+	}
+	if ta.Comparable() {
+		return a == b
+	}
+	return a.Name() == b.Name()
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import "flag"
+
+// helpCommand shows the usage of the program or of a command. Help asked for on the command line
+// is handled as the args are parsed, without going through the program's RunFunc, but the command
+// lists help alongside the others and lets it be run like them, e.g. with Context.Run. A command of
+// the program's own called help replaces it.
+type helpCommand struct {
+	p *Program
+}
+
+const helpHelp = `
+Show the usage of the program, or of command when one is given. With -all hidden commands and
+flags are included.
+`
+
+func (c *helpCommand) Name() string              { return "help" }
+func (c *helpCommand) Args() string              { return "[-all] [command]" }
+func (c *helpCommand) Desc() string              { return "Show help for a command" }
+func (c *helpCommand) Help() string              { return helpHelp }
+func (c *helpCommand) Register(fs *flag.FlagSet) {}
+
+// Run prints the usage of the command named by args, or returns the program usage as an error for
+// Run's caller to print when args are empty, just as "prog help" does. Either way the error
+// matches ErrHelpRequested.
+func (c *helpCommand) Run(ctx Context, args []string) error {
+	all := false
+	if len(args) > 0 && isAllFlag(args[0]) {
+		all, args = true, args[1:]
+	}
+	if len(args) == 0 {
+		return &usageError{usage: c.p.usage(all)}
+	}
+
+	cmd, err := c.p.lookupCommand(args[0])
+	if err != nil {
+		return err
+	}
+	if cmd, _, err = c.p.descend(cmd, args[1:]); err != nil {
+		return err
+	}
+	c.p.printCommandUsage(c.p.Out.Writer(), c.p.scratchFlagSet(cmd), cmd, all)
+	return ErrHelpRequested
+}
+
+// isHelpToken checks whether arg, given in place of a command name, asks for help. A command of
+// the program's own with the same name takes precedence.
+func (p *Program) isHelpToken(arg string) bool {
+	if !p.isHelp(arg) {
+		return false
+	}
+	for _, cmd := range p.commands {
+		if hasName(cmd, arg) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,43 @@
+package cmd
+
+// Hider is implemented by commands that can be hidden from the program usage, such as internal or
+// experimental commands. Hidden commands can still be run and have help of their own, and are
+// listed by "help --all" and "commands -all".
+type Hider interface {
+	Hidden() bool
+}
+
+// isHiddenCommand checks whether cmd should be left out of the program usage
+func isHiddenCommand(cmd Command) bool {
+	h, ok := cmd.(Hider)
+	return ok && h.Hidden()
+}
+
+// visibleCommands returns the commands of cmds that aren't hidden, or all of them when all is set
+func visibleCommands(cmds []Command, all bool) []Command {
+	if all {
+		return cmds
+	}
+
+	var visible []Command
+	for _, cmd := range cmds {
+		if !isHiddenCommand(cmd) {
+			visible = append(visible, cmd)
+		}
+	}
+	return visible
+}
+
+// helpMode is how much usage was requested
+type helpMode int
+
+const (
+	noHelp    helpMode = iota
+	helpUsage          // the usage
+	helpAll            // the usage including hidden commands and flags, requested with "help --all"
+)
+
+// isAllFlag checks whether arg asks help for hidden commands and flags too
+func isAllFlag(arg string) bool {
+	return arg == "-all" || arg == "--all"
+}
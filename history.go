@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// WithUsageHistory keeps a count of how often each command is run successfully in the program's
+// data dir, and uses it to rank the commands suggested for an unknown command so the close matches
+// the user runs most come first. Only command names are recorded, never their arguments, and the
+// history never leaves the machine. It is off unless this option is given.
+func WithUsageHistory() Option {
+	return func(p *Program) {
+		p.history = true
+		p.Use(p.recordHistory)
+	}
+}
+
+// historyPath returns the file the usage history is kept in
+func (p *Program) historyPath() (string, error) {
+	dir, err := p.DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "history.json"), nil
+}
+
+// readHistory returns the number of times each command has been run. A missing or unreadable
+// history is treated as empty.
+func (p *Program) readHistory() map[string]int {
+	counts := make(map[string]int)
+	path, err := p.historyPath()
+	if err != nil {
+		return counts
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return counts
+	}
+	json.Unmarshal(b, &counts)
+	return counts
+}
+
+// recordHistory is middleware counting the successful runs of each command
+func (p *Program) recordHistory(next RunFunc) RunFunc {
+	return func(env *Environment, cmd Command, args []string) error {
+		err := next(env, cmd, args)
+		if err != nil {
+			return err
+		}
+
+		path, perr := p.historyPath()
+		if perr != nil {
+			return nil
+		}
+		counts := p.readHistory()
+		counts[cmd.Name()]++
+		if b, jerr := json.Marshal(counts); jerr == nil {
+			if werr := replaceFile(path, b); werr != nil {
+				p.env.warn("unable to record usage history: %v", werr)
+			}
+		}
+		return nil
+	}
+}
+
+// suggestCommands returns the names of the visible commands close to name, by edit distance or as
+// a prefix, the closest first. When usage history is kept the ones run most often come first.
+func (p *Program) suggestCommands(name string) []string {
+	if name == "" {
+		return nil
+	}
+
+	type suggestion struct {
+		name     string
+		distance int
+		count    int
+	}
+
+	counts := make(map[string]int)
+	if p.history {
+		counts = p.readHistory()
+	}
+	var matches []suggestion
+	for _, cmd := range visibleCommands(p.allCommands(), false) {
+		d := editDistance(name, cmd.Name())
+		if d <= maxSuggestionDistance(name) || strings.HasPrefix(cmd.Name(), name) {
+			matches = append(matches, suggestion{cmd.Name(), d, counts[cmd.Name()]})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].count != matches[j].count {
+			return matches[i].count > matches[j].count
+		}
+		if matches[i].distance != matches[j].distance {
+			return matches[i].distance < matches[j].distance
+		}
+		return matches[i].name < matches[j].name
+	})
+
+	var names []string
+	for i, s := range matches {
+		if i == maxSuggestions {
+			break
+		}
+		names = append(names, s.name)
+	}
+	return names
+}
+
+// maxSuggestions is how many commands are suggested for an unknown command at most
+const maxSuggestions = 3
+
+// maxSuggestionDistance is how many edits away from name a command can be to be suggested
+func maxSuggestionDistance(name string) int {
+	if n := len(name) / 3; n > 2 {
+		return n
+	}
+	return 2
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		cur := make([]int, len(rb)+1)
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = prev[j-1] + cost
+			if d := prev[j] + 1; d < cur[j] {
+				cur[j] = d
+			}
+			if d := cur[j-1] + 1; d < cur[j] {
+				cur[j] = d
+			}
+		}
+		prev = cur
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"syscall"
+)
+
+// ErrBrokenPipe is returned when output is written after the reading end of a pipe has gone away,
+// for example when piping into head. Commands usually want to stop quietly when they see it.
+var ErrBrokenPipe = errors.New("broken pipe")
+
+// JSONLinesWriter writes values to a context's stdout as JSON Lines, one JSON object per line,
+// which streams well into tools like jq.
+type JSONLinesWriter struct {
+	w   io.Writer
+	enc *json.Encoder
+	err error
+}
+
+// NewJSONLinesWriter returns a JSONLinesWriter writing to the stdout of ctx.
+func NewJSONLinesWriter(ctx Context) *JSONLinesWriter {
+	w := ctx.Stdout()
+	return &JSONLinesWriter{
+		w:   w,
+		enc: json.NewEncoder(w),
+	}
+}
+
+// Encode writes v as a single line of JSON, flushing stdout when it is buffered so each record is
+// seen as soon as it is written. Once a write has failed every later call returns the same error,
+// Encode returns ErrBrokenPipe when the reader has gone away.
+func (jw *JSONLinesWriter) Encode(v interface{}) error {
+	if jw.err != nil {
+		return jw.err
+	}
+
+	err := jw.enc.Encode(v)
+	if f, ok := jw.w.(interface{ Flush() error }); ok && err == nil {
+		err = f.Flush()
+	}
+
+	var je *json.UnsupportedTypeError
+	var jv *json.UnsupportedValueError
+	switch {
+	case err == nil:
+	case errors.As(err, &je), errors.As(err, &jv):
+		// the value couldn't be encoded, nothing was written so later values can still be
+		return err
+	case errors.Is(err, syscall.EPIPE), errors.Is(err, io.ErrClosedPipe):
+		jw.err = ErrBrokenPipe
+	default:
+		jw.err = err
+	}
+	return jw.err
+}
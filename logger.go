@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"io"
+	"log/slog"
+)
+
+// WithLogger routes the diagnostics commands log through Context.Logger to l. By default they are
+// written to the program's stderr by a slog.TextHandler, without timestamps, as in:
+//
+//	level=INFO msg="uploading release" version=1.2.0
+func WithLogger(l *slog.Logger) Option {
+	return func(p *Program) {
+		p.env.logger = l
+	}
+}
+
+// newTextLogger returns the default logger, writing text records without the time to w
+func newTextLogger(w io.Writer) *slog.Logger {
+	return slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+}
@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// GenerateManPage writes a man page for the program to w in roff, for packagers to install as
+// prog.1. It has NAME, SYNOPSIS and DESCRIPTION sections from the program and its default command,
+// FLAGS for the program wide flags, and COMMANDS with the synopsis, help, flags and examples of
+// every command, down through command groups. Hidden commands and flags are left out.
+func (p *Program) GenerateManPage(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	title := p.name
+	if p.version != "" {
+		title += " " + p.version
+	}
+	fmt.Fprintf(bw, ".TH %s 1 \"\" %s\n", manQuote(strings.ToUpper(p.name)), manQuote(title))
+
+	fmt.Fprintln(bw, ".SH NAME")
+	if desc := strings.TrimSpace(p.desc); desc != "" {
+		fmt.Fprintf(bw, "%s \\- %s\n", manEscape(p.name), manEscape(desc))
+	} else {
+		fmt.Fprintln(bw, manEscape(p.name))
+	}
+
+	fmt.Fprintln(bw, ".SH SYNOPSIS")
+	if p.root != nil {
+		manSynopsis(bw, p.name, p.synopsis(p.scratchFlagSet(p.root), p.root))
+	}
+	if len(p.commands) > 0 {
+		if p.root != nil {
+			fmt.Fprintln(bw, ".br")
+		}
+		fmt.Fprintf(bw, "\\fB%s\\fR \\fIcommand\\fR [\\fIargs\\fR]\n", manEscape(p.name))
+	}
+
+	desc := strings.TrimSpace(p.desc)
+	if p.root != nil {
+		if help := strings.TrimSpace(p.root.Help()); help != "" {
+			desc = strings.TrimSpace(desc + "\n\n" + help)
+		}
+	}
+	if desc != "" {
+		fmt.Fprintln(bw, ".SH DESCRIPTION")
+		manParagraphs(bw, desc)
+	}
+
+	fs := p.flags
+	if p.root != nil {
+		fs = p.scratchFlagSet(p.root)
+	}
+	if flags := groupFlags(fs, false, nil); len(flags) > 0 {
+		fmt.Fprintln(bw, ".SH FLAGS")
+		manFlags(bw, flags)
+	}
+
+	if cmds := visibleCommands(p.allCommands(), false); len(cmds) > 0 {
+		fmt.Fprintln(bw, ".SH COMMANDS")
+		p.manCommands(bw, cmds)
+	}
+
+	return bw.Flush()
+}
+
+// manCommands writes a subsection for each of cmds followed by their subcommands
+func (p *Program) manCommands(w io.Writer, cmds []Command) {
+	for _, cmd := range cmds {
+		fs := p.scratchFlagSet(cmd)
+		path := p.commandPath(cmd)
+		fmt.Fprintf(w, ".SS %s\n", manQuote(strings.TrimPrefix(path, p.name+" ")))
+		if desc := strings.TrimSpace(cmd.Desc()); desc != "" {
+			fmt.Fprintln(w, manEscape(desc))
+			fmt.Fprintln(w, ".PP")
+		}
+		manSynopsis(w, path, p.synopsis(fs, cmd))
+		if aliases := commandAliases(cmd); len(aliases) > 0 {
+			fmt.Fprintln(w, ".PP")
+			fmt.Fprintf(w, "Aliases: %s\n", manEscape(strings.Join(aliases, ", ")))
+		}
+		if help := strings.TrimSpace(cmd.Help()); help != "" {
+			fmt.Fprintln(w, ".PP")
+			manParagraphs(w, help)
+		}
+
+		flags := groupFlags(fs, false, func(f *flag.Flag) bool {
+			return !p.isGlobalFlag(f)
+		})
+		if len(flags) > 0 {
+			fmt.Fprintln(w, ".PP")
+			fmt.Fprintln(w, "Flags:")
+			manFlags(w, flags)
+		}
+		if examples := commandExamples(cmd); len(examples) > 0 {
+			fmt.Fprintln(w, ".PP")
+			fmt.Fprintln(w, "Examples:")
+			for _, ex := range examples {
+				fmt.Fprintf(w, ".TP\n\\fB%s\\fR\n%s\n", manEscape(ex.Command), manEscape(ex.Desc))
+			}
+		}
+
+		p.manCommands(w, visibleCommands(subcommands(cmd), false))
+	}
+}
+
+// manSynopsis writes a synopsis such as "greet [-p|-pirate] [name]" with path, how the command is
+// called, in bold
+func manSynopsis(w io.Writer, path, synopsis string) {
+	rest := strings.TrimPrefix(synopsis, path)
+	fmt.Fprintf(w, ".nh\n.ad l\n\\fB%s\\fR%s\n.ad\n.hy\n", manEscape(path), manEscape(rest))
+}
+
+// manFlags writes a tagged paragraph for each group of flags, the names of the same flag together
+func manFlags(w io.Writer, groups [][]*flag.Flag) {
+	for _, group := range groups {
+		names := make([]string, len(group))
+		for i, f := range group {
+			names[i] = "\\fB" + manEscape("-"+f.Name) + "\\fR"
+		}
+
+		f := group[len(group)-1]
+		tag := strings.Join(names, ", ")
+		if !isBoolFlag(f) {
+			name, _ := unquoteUsage(f)
+			if name == "" {
+				name = "value"
+			}
+			tag += " \\fI" + manEscape(name) + "\\fR"
+		}
+
+		_, usage := unquoteUsage(f)
+		fmt.Fprintf(w, ".TP\n%s\n", tag)
+		fmt.Fprintf(w, "%s (default: %s)\n", manEscape(usage), manEscape(prettyDefaultValue(defaultString(f))))
+	}
+}
+
+// manParagraphs writes text with a paragraph break for each blank line
+func manParagraphs(w io.Writer, text string) {
+	for i, para := range strings.Split(text, "\n\n") {
+		if i > 0 {
+			fmt.Fprintln(w, ".PP")
+		}
+		for _, line := range strings.Split(strings.TrimSpace(para), "\n") {
+			fmt.Fprintln(w, manEscape(strings.TrimSpace(line)))
+		}
+	}
+}
+
+// manEscape escapes s for roff, so backslashes, dashes and lines starting with a control
+// character are printed as they are
+func manEscape(s string) string {
+	s = strings.NewReplacer(`\`, `\e`, "-", `\-`).Replace(s)
+	if strings.HasPrefix(s, ".") || strings.HasPrefix(s, "'") {
+		s = `\&` + s
+	}
+	return s
+}
+
+// manQuote escapes s as a quoted argument to a roff request
+func manQuote(s string) string {
+	return `"` + strings.ReplaceAll(manEscape(s), `"`, `""`) + `"`
+}
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// findCommand finds the command matching name. Names and aliases are matched exactly, then
+// ignoring case when enabled, then deprecated names, then with prefix matching enabled prefixes and
+// finally the patterns of commands implementing Matcher. It returns nil if nothing matches and an
+// ErrAmbiguousCommand if more than one command does.
+func (p *Program) findCommand(name string) (Command, error) {
+	if name == "" {
+		return nil, nil
+	}
+
+	var matches []Command
+	for _, cmd := range p.allCommands() {
+		if hasName(cmd, name) {
+			matches = append(matches, cmd)
+		}
+	}
+
+	if len(matches) == 0 && p.foldCase {
+		for _, cmd := range p.allCommands() {
+			if hasNameFold(cmd, name) {
+				matches = append(matches, cmd)
+			}
+		}
+	}
+
+	if len(matches) == 0 {
+		if cmd := p.forwardDeprecated(name); cmd != nil {
+			matches = append(matches, cmd)
+		}
+	}
+
+	if len(matches) == 0 && p.prefixMatch {
+		for _, cmd := range p.allCommands() {
+			for _, n := range append([]string{cmd.Name()}, commandAliases(cmd)...) {
+				if strings.HasPrefix(n, name) {
+					matches = append(matches, cmd)
+					break
+				}
+			}
+		}
+	}
+
+	if len(matches) == 0 {
+		matches = patternMatches(p.allCommands(), name)
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, nil
+	case 1:
+		return matches[0], nil
+	}
+	return nil, &ErrAmbiguousCommand{
+		programName: p.name,
+		commandName: name,
+		candidates:  matches,
+	}
+}
+
+// Matcher is implemented by commands called by a pattern rather than a fixed name, such as
+// "pod/NAME" in "prog pod/web". Match reports whether arg calls the command, and the command is
+// given arg as its first argument. Patterns are only tried when no command has arg as its name,
+// alias or prefix.
+type Matcher interface {
+	Match(arg string) bool
+}
+
+// patternMatches returns the commands of cmds whose pattern matches arg
+func patternMatches(cmds []Command, arg string) []Command {
+	var matches []Command
+	for _, cmd := range cmds {
+		if m, ok := cmd.(Matcher); ok && m.Match(arg) {
+			matches = append(matches, cmd)
+		}
+	}
+	return matches
+}
+
+// isPatternMatch checks whether cmd was called by its pattern, rather than its name, an alias or a
+// prefix of them
+func (p *Program) isPatternMatch(cmd Command, arg string) bool {
+	m, ok := cmd.(Matcher)
+	if !ok || hasName(cmd, arg) || !m.Match(arg) {
+		return false
+	}
+
+	if p.prefixMatch {
+		for _, n := range append([]string{cmd.Name()}, commandAliases(cmd)...) {
+			if strings.HasPrefix(n, arg) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// ErrAmbiguousCommand is returned when the requested command matches more than one command, either
+// through a shared alias, a prefix or a pattern. The message lists each candidate with its
+// description:
+//
+//	prog: d: ambiguous command, it could be:
+//
+//	  deploy  deploy the site
+//	  diff    show changes
+type ErrAmbiguousCommand struct {
+	programName string
+	commandName string
+	candidates  []Command
+}
+
+// Error implements the error interface
+func (e *ErrAmbiguousCommand) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %s: ambiguous command, it could be:\n\n", e.programName, e.commandName)
+
+	t := newTable(2)
+	for _, cmd := range e.candidates {
+		t.row("", cmd.Name(), cmd.Desc())
+	}
+	t.write(&b)
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
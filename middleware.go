@@ -0,0 +1,105 @@
+package cmd
+
+// RunFunc runs a command with the args left after parsing its flags, it is the callback given to
+// Program.Run. Args after a "--" terminator are passed on verbatim, without the "--", so
+// "greet -- -p" greets "-p".
+type RunFunc func(*Environment, Command, []string) error
+
+// PreRunner is implemented by commands that need to do something before they are run. An error
+// stops the command from running. A group implementing it has PreRun called before each of its
+// subcommands is run.
+type PreRunner interface {
+	PreRun(Context, []string) error
+}
+
+// PostRunner is implemented by commands that need to do something after they are run. PostRun is
+// given the error the command returned, if any, and returns the error to report in its place. A
+// group implementing it has PostRun called after each of its subcommands is run.
+type PostRunner interface {
+	PostRun(Context, []string, error) error
+}
+
+// Use adds middleware wrapping the dispatch of every command. Middleware composes in the order it
+// is added, so the first added is the outermost.
+//
+// Running a command goes through each layer in turn:
+//
+//	Before -> middleware -> group PreRun -> PreRun -> fn
+//	 After <-            <- group PostRun <- PostRun <-
+//
+// where fn is the RunFunc given to Run and the group hooks are those of the groups the command is
+// in, such as remote for "prog remote add", the outermost group first on the way in and last on the
+// way out. An error on the way in stops the layers beneath it from running, PostRun still sees the
+// error returned by fn and After always runs.
+func (p *Program) Use(mw func(next RunFunc) RunFunc) {
+	p.middleware = append(p.middleware, mw)
+}
+
+// Before adds a hook run before every command, such as to open a database. An error stops the
+// command from running and is returned from Run. Hooks run in the order they are added.
+func (p *Program) Before(hook func(*Environment, Command) error) {
+	p.before = append(p.before, hook)
+}
+
+// After adds a hook run after every command, even when it or a Before hook failed, given the error
+// Run is about to return for logging or cleanup. Hooks run in the order they are added.
+func (p *Program) After(hook func(*Environment, Command, error)) {
+	p.after = append(p.after, hook)
+}
+
+// chain wraps fn in the command hooks, middleware and the program's Before and After hooks
+func (p *Program) chain(fn RunFunc) RunFunc {
+	run := p.commandHooks(fn)
+	for i := len(p.middleware) - 1; i >= 0; i-- {
+		run = p.middleware[i](run)
+	}
+	if len(p.before) == 0 && len(p.after) == 0 {
+		return run
+	}
+
+	return func(env *Environment, cmd Command, args []string) (err error) {
+		defer func() {
+			for _, hook := range p.after {
+				hook(env, cmd, err)
+			}
+		}()
+
+		for _, hook := range p.before {
+			if err := hook(env, cmd); err != nil {
+				return err
+			}
+		}
+		return run(env, cmd, args)
+	}
+}
+
+// commandHooks wraps fn with the PreRun and PostRun of the command and of the groups it is in
+func (p *Program) commandHooks(fn RunFunc) RunFunc {
+	return func(env *Environment, cmd Command, args []string) error {
+		run := func() error {
+			return runHooks(env, cmd, args, func() error { return fn(env, cmd, args) })
+		}
+		groups := p.parentGroups(cmd)
+		for i := len(groups) - 1; i >= 0; i-- {
+			group, next := groups[i], run
+			run = func() error { return runHooks(env, group, args, next) }
+		}
+		return run()
+	}
+}
+
+// runHooks runs next between the PreRun and PostRun of cmd
+func runHooks(env *Environment, cmd Command, args []string, next func() error) error {
+	if pr, ok := cmd.(PreRunner); ok {
+		if err := pr.PreRun(env.GetDefaultContext(), args); err != nil {
+			return err
+		}
+	}
+
+	err := next()
+
+	if pr, ok := cmd.(PostRunner); ok {
+		err = pr.PostRun(env.GetDefaultContext(), args, err)
+	}
+	return err
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import "strings"
+
+// namespace is a set of commands sharing a name prefix
+type namespace struct {
+	name     string
+	commands []Command
+}
+
+// groupByNamespace splits cmds into those without a namespace and the namespaces in the order they
+// first appear. Without a namespace separator all commands are returned ungrouped.
+func (p *Program) groupByNamespace(cmds []Command) ([]Command, []*namespace) {
+	if p.nsSeparator == "" {
+		return cmds, nil
+	}
+
+	var (
+		top        []Command
+		namespaces []*namespace
+		index      = make(map[string]*namespace)
+	)
+	for _, cmd := range cmds {
+		name, _, ok := strings.Cut(cmd.Name(), p.nsSeparator)
+		if !ok || name == "" {
+			top = append(top, cmd)
+			continue
+		}
+
+		ns, ok := index[name]
+		if !ok {
+			ns = &namespace{name: name}
+			index[name] = ns
+			namespaces = append(namespaces, ns)
+		}
+		ns.commands = append(ns.commands, cmd)
+	}
+	return top, namespaces
+}
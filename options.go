@@ -0,0 +1,103 @@
+package cmd
+
+import "io"
+
+// Option configures a Program.
+type Option func(*Program)
+
+// WithSnapshot enables snapshot mode, producing the most deterministic rendering of the usage
+// output: a fixed terminal width, no color, no pager and stable ordering throughout. It is meant
+// for golden file tests of help output and can also be enabled by setting PROG_SNAPSHOT=1, where
+// PROG is the upper cased program name.
+func WithSnapshot(enabled bool) Option {
+	return func(p *Program) {
+		p.snapshot = enabled
+	}
+}
+
+// WithNamespaceSeparator groups commands whose names share a namespace prefix, such as db:migrate
+// and db:seed with a separator of ":", under their own heading in the program usage. Namespaced
+// names are still matched exactly.
+func WithNamespaceSeparator(sep string) Option {
+	return func(p *Program) {
+		p.nsSeparator = sep
+	}
+}
+
+// WithHelpTokens replaces the arguments that trigger help, by default "help", "-help", "--help"
+// and "-h". Tokens are matched case-insensitively, an empty set disables help dispatch.
+func WithHelpTokens(tokens []string) Option {
+	return func(p *Program) {
+		p.helpTokens = tokens
+	}
+}
+
+// WithAliasesInUsage lists each command's aliases next to its name in the program usage, e.g.
+// "commit, ci". By default aliases are only shown in the command's own help.
+func WithAliasesInUsage(enabled bool) Option {
+	return func(p *Program) {
+		p.aliasUsage = enabled
+	}
+}
+
+// WithPrefixMatching lets commands be called by any unambiguous prefix of their name or aliases,
+// e.g. "st" for "status". Exact matches always win.
+func WithPrefixMatching(enabled bool) Option {
+	return func(p *Program) {
+		p.prefixMatch = enabled
+	}
+}
+
+// WithNameFromArgs runs the program under the name it was called by, the base of args[0], as with
+// Program.RunAs.
+func WithNameFromArgs() Option {
+	return func(p *Program) {
+		p.nameFromArgs = true
+	}
+}
+
+// WithStdin sets the stdin commands read from, which is os.Stdin by default.
+func WithStdin(r io.Reader) Option {
+	return func(p *Program) {
+		p.env.SetStdin(r)
+	}
+}
+
+// WithStdout sets the stdout commands write to, which is os.Stdout by default.
+func WithStdout(w io.Writer) Option {
+	return func(p *Program) {
+		p.env.stdout = w
+	}
+}
+
+// WithStderr sets the stderr usage, prompts and progress events are written to, which is os.Stderr
+// by default.
+func WithStderr(w io.Writer) Option {
+	return func(p *Program) {
+		p.env.stderr = w
+	}
+}
+
+// WithEnviron sets the environment commands run in as "key=value" pairs, which is os.Environ() by
+// default. Flags bound to environment variables are read from it too.
+func WithEnviron(env []string) Option {
+	return func(p *Program) {
+		p.env.Env = env
+	}
+}
+
+// WithWorkingDir sets the working directory commands are given, which is the process's own by
+// default.
+func WithWorkingDir(dir string) Option {
+	return func(p *Program) {
+		p.env.WorkingDir = dir
+	}
+}
+
+// WithCaseInsensitiveCommands matches command names and aliases ignoring case, so "Greet" runs
+// greet. Exact matches still win, and commands keep their own names in usage and when run.
+func WithCaseInsensitiveCommands(enabled bool) Option {
+	return func(p *Program) {
+		p.foldCase = enabled
+	}
+}
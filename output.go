@@ -0,0 +1,203 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// OutputFormatter is implemented by commands that can write their results in several formats, such
+// as json, yaml, table or text. Commands implementing it get an -output flag, or -o, accepting one
+// of the formats and defaulting to the first. The chosen format is available from OutputFormat and
+// Encode writes a value in it.
+type OutputFormatter interface {
+	OutputFormats() []string
+}
+
+// WithJSONOutput adds the -json flag, which has every command write its results as JSON: the output
+// format of the running command becomes json, overriding any -output given, so Context.Encode
+// writes JSON to stdout.
+func WithJSONOutput() Option {
+	return func(p *Program) {
+		p.flags.BoolVar(&p.jsonOutput, "json", false, "Write results as JSON")
+	}
+}
+
+// outputFormatKey is the context key holding the chosen output format
+type outputFormatKey struct{}
+
+// OutputFormat returns the output format chosen for the running command, or an empty string when
+// the command doesn't implement OutputFormatter.
+func OutputFormat(ctx Context) string {
+	f, _ := ctx.Value(outputFormatKey{}).(string)
+	return f
+}
+
+// Encode writes v to the stdout of ctx in the chosen output format. The formats understood are
+// json, yaml, table and text. A table is written from a [][]string, or a value with a Rows()
+// [][]string method, whose first row is the heading. JSON is written on a single line, for scripts
+// to read line by line. Text is written as v's String method, or as with fmt.Println.
+func Encode(ctx Context, v interface{}) error {
+	return encode(ctx.Stdout(), OutputFormat(ctx), v)
+}
+
+func encode(w io.Writer, format string, v interface{}) error {
+	switch format {
+	case "json":
+		return json.NewEncoder(w).Encode(v)
+	case "yaml":
+		return encodeYAML(w, v)
+	case "table":
+		return encodeTable(w, v)
+	case "text", "":
+		if s, ok := v.(fmt.Stringer); ok {
+			_, err := fmt.Fprintln(w, s.String())
+			return err
+		}
+		_, err := fmt.Fprintln(w, v)
+		return err
+	}
+	return fmt.Errorf("unsupported output format %q", format)
+}
+
+func encodeTable(w io.Writer, v interface{}) error {
+	var rows [][]string
+	switch t := v.(type) {
+	case [][]string:
+		rows = t
+	case interface{ Rows() [][]string }:
+		rows = t.Rows()
+	default:
+		return fmt.Errorf("cannot write %T as a table", v)
+	}
+
+	t := newTable(2)
+	for _, r := range rows {
+		if len(r) > 0 {
+			t.row(r...)
+		}
+	}
+	return t.write(w)
+}
+
+// encodeYAML writes v as YAML block style. The value goes through encoding/json first, so struct
+// tags apply as they do for json, and scalars are written as JSON which YAML accepts as is.
+func encodeYAML(w io.Writer, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	var generic interface{}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&generic); err != nil {
+		return err
+	}
+
+	var out strings.Builder
+	writeYAML(&out, generic, 0, false)
+	_, err = io.WriteString(w, out.String())
+	return err
+}
+
+// writeYAML writes v at the given indent, each value ending its own line. When inline is set the
+// first line follows a list dash and isn't padded.
+func writeYAML(b *strings.Builder, v interface{}, indent int, inline bool) {
+	if !isYAMLBlock(v) {
+		s, _ := json.Marshal(v)
+		b.Write(s)
+		b.WriteByte('\n')
+		return
+	}
+
+	pad := strings.Repeat("  ", indent)
+	switch t := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for i, k := range keys {
+			if i > 0 || !inline {
+				b.WriteString(pad)
+			}
+			b.WriteString(strconv.Quote(k) + ":")
+			if isYAMLBlock(t[k]) {
+				b.WriteByte('\n')
+				writeYAML(b, t[k], indent+1, false)
+				continue
+			}
+			b.WriteByte(' ')
+			writeYAML(b, t[k], indent, true)
+		}
+	case []interface{}:
+		for i, e := range t {
+			if i > 0 || !inline {
+				b.WriteString(pad)
+			}
+			b.WriteString("- ")
+			writeYAML(b, e, indent+1, true)
+		}
+	}
+}
+
+// isYAMLBlock reports whether v is written as a block, empty maps and lists are written inline as
+// {} and []
+func isYAMLBlock(v interface{}) bool {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		return len(t) > 0
+	case []interface{}:
+		return len(t) > 0
+	}
+	return false
+}
+
+// outputFormats returns the output formats cmd supports, if any
+func outputFormats(cmd Command) []string {
+	if o, ok := cmd.(OutputFormatter); ok {
+		return o.OutputFormats()
+	}
+	return nil
+}
+
+// withOutputFormat records the chosen output format in the environment's context until the
+// returned func is called
+func (e *Environment) withOutputFormat(format string) func() {
+	parent := e.context()
+	e.ctx = context.WithValue(parent, outputFormatKey{}, format)
+	return func() {
+		e.ctx = parent
+	}
+}
+
+// choiceValue is a flag.Value restricted to a set of choices
+type choiceValue struct {
+	choices []string
+	value   string
+}
+
+func newChoiceValue(choices []string) *choiceValue {
+	return &choiceValue{choices: choices, value: choices[0]}
+}
+
+func (c *choiceValue) String() string    { return c.value }
+func (c *choiceValue) Get() interface{}  { return c.value }
+func (c *choiceValue) Choices() []string { return c.choices }
+
+func (c *choiceValue) Set(s string) error {
+	for _, choice := range c.choices {
+		if s == choice {
+			c.value = s
+			return nil
+		}
+	}
+	return fmt.Errorf("must be one of %s", strings.Join(c.choices, ", "))
+}
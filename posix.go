@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"flag"
+	"strings"
+)
+
+// WithPosixShortFlags lets single letter flags be clustered as POSIX utilities allow, so -vxf is
+// -v -x -f and -ofile is -o file. Expansion only happens when every letter is a flag, the last of
+// which may take a value, and when no flag is named by the whole cluster, so a flag called -vxf
+// still wins.
+func WithPosixShortFlags(enabled bool) Option {
+	return func(p *Program) {
+		p.posixShort = enabled
+	}
+}
+
+// WithInterspersedFlags lets flags be given after a command's positional arguments, so "greet bob
+// -p" is "greet -p bob", rather than flag parsing stopping at the first argument that isn't a flag.
+// Everything after a "--" is still passed to the command as it is.
+func WithInterspersedFlags(enabled bool) Option {
+	return func(p *Program) {
+		p.interspersed = enabled
+	}
+}
+
+// intersperseFlags moves the flags in args, along with their values, ahead of the positional
+// arguments, which follow a "--" so none of them are mistaken for flags. With posixShort, clusters
+// of single letter flags are recognised too.
+func intersperseFlags(fs *flag.FlagSet, args []string, posixShort bool) []string {
+	var flags, positional []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			positional = append(positional, args[i+1:]...)
+			break
+		}
+		name, _, hasValue := splitFlag(arg)
+		if name == "" {
+			positional = append(positional, arg)
+			continue
+		}
+
+		flags = append(flags, arg)
+		needsValue := false
+		if f := fs.Lookup(name); f != nil {
+			needsValue = !hasValue && !isBoolFlag(f)
+		} else if posixShort && !hasValue && !strings.HasPrefix(arg, "--") {
+			_, needsValue, _ = splitCluster(fs, name)
+		}
+		if needsValue && i+1 < len(args) {
+			i++
+			flags = append(flags, args[i])
+		}
+	}
+
+	if len(positional) == 0 {
+		return flags
+	}
+	return append(append(flags, "--"), positional...)
+}
+
+// expandShortFlags expands the clusters of single letter flags in args, up to the first argument
+// that isn't a flag
+func expandShortFlags(fs *flag.FlagSet, args []string) []string {
+	expanded := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		name, _, hasValue := splitFlag(arg)
+		if name == "" {
+			return append(expanded, args[i:]...)
+		}
+
+		if f := fs.Lookup(name); f != nil || hasValue || strings.HasPrefix(arg, "--") {
+			expanded = append(expanded, arg)
+			if f != nil && !hasValue && !isBoolFlag(f) && i+1 < len(args) {
+				i++
+				expanded = append(expanded, args[i])
+			}
+			continue
+		}
+
+		cluster, needsValue, ok := splitCluster(fs, name)
+		if !ok {
+			expanded = append(expanded, arg)
+			continue
+		}
+		expanded = append(expanded, cluster...)
+		if needsValue && i+1 < len(args) {
+			i++
+			expanded = append(expanded, args[i])
+		}
+	}
+	return expanded
+}
+
+// splitCluster splits a cluster of single letter flags such as vxf, or ofile where -o takes a
+// value, into separate args. It reports whether the last flag still needs the next argument as its
+// value and false if the cluster isn't made of single letter flags.
+func splitCluster(fs *flag.FlagSet, cluster string) (args []string, needsValue bool, ok bool) {
+	for i, r := range cluster {
+		f := fs.Lookup(string(r))
+		if f == nil {
+			return nil, false, false
+		}
+
+		args = append(args, "-"+f.Name)
+		if !isBoolFlag(f) {
+			if rest := cluster[i+len(string(r)):]; rest != "" {
+				return append(args, rest), false, true
+			}
+			return args, true, true
+		}
+	}
+	return args, false, true
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import "fmt"
+
+// Precondition is something that must hold before a command can run, such as being logged in or a
+// config file existing. Check returns an error when it doesn't hold and Remediation tells the user
+// how to fix that, e.g. "run `prog login`".
+type Precondition interface {
+	Check(Context) error
+	Remediation() string
+}
+
+// Preconditioner is implemented by commands with preconditions. They are checked in order before
+// the command is run, and before the user is asked to confirm it, the first to fail stops it with
+// an ErrPreconditionFailed.
+type Preconditioner interface {
+	Preconditions() []Precondition
+}
+
+// Require returns a Precondition checked by check, with remediation as the way to fix it.
+func Require(check func(Context) error, remediation string) Precondition {
+	return &precondition{check: check, remediation: remediation}
+}
+
+type precondition struct {
+	check       func(Context) error
+	remediation string
+}
+
+func (p *precondition) Check(ctx Context) error { return p.check(ctx) }
+func (p *precondition) Remediation() string     { return p.remediation }
+
+// ErrPreconditionFailed is returned when a precondition of a command doesn't hold. The message
+// gives the reason followed by the remediation:
+//
+//	prog: deploy: not logged in; run `prog login`
+type ErrPreconditionFailed struct {
+	programName string
+	commandName string
+	cause       error
+	remediation string
+}
+
+// Error implements the error interface
+func (e *ErrPreconditionFailed) Error() string {
+	if e.remediation == "" {
+		return fmt.Sprintf("%s: %s: %v", e.programName, e.commandName, e.cause)
+	}
+	return fmt.Sprintf("%s: %s: %v; %s", e.programName, e.commandName, e.cause, e.remediation)
+}
+
+// Unwrap returns the error the failed check returned
+func (e *ErrPreconditionFailed) Unwrap() error {
+	return e.cause
+}
+
+// Remediation returns how the user can make the precondition hold
+func (e *ErrPreconditionFailed) Remediation() string {
+	return e.remediation
+}
+
+// checkPreconditions checks the preconditions of cmd in order, returning the first to fail
+func (p *Program) checkPreconditions(ctx Context, cmd Command) error {
+	pc, ok := cmd.(Preconditioner)
+	if !ok {
+		return nil
+	}
+
+	for _, c := range pc.Preconditions() {
+		if err := c.Check(ctx); err != nil {
+			return &ErrPreconditionFailed{
+				programName: p.name,
+				commandName: cmd.Name(),
+				cause:       err,
+				remediation: c.Remediation(),
+			}
+		}
+	}
+	return nil
+}
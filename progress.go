@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// Event is a progress or status event a command emits with Context.Emit, for front-ends such as
+// GUIs driving the program. Type is the kind of event, e.g. "progress", "status" or "done".
+type Event struct {
+	Type    string  `json:"type"`
+	Message string  `json:"message,omitempty"`
+	Percent float64 `json:"percent,omitempty"`
+}
+
+// WithProgressEvents adds the -progress flag. Given -progress=json the events commands emit with
+// Context.Emit are written to stderr as JSON Lines, one event per line, so they stay apart from
+// results written to stdout. Otherwise emitting an event does nothing.
+func WithProgressEvents() Option {
+	return func(p *Program) {
+		p.progress = newChoiceValue([]string{"none", "json"})
+		p.flags.Var(p.progress, "progress", "Progress event format, one of none, json")
+	}
+}
+
+// emitEvents points the environment's events at stderr, when -progress=json is given, until the
+// returned func is called
+func (p *Program) emitEvents() func() {
+	if p.progress == nil || p.progress.value != "json" {
+		return func() {}
+	}
+
+	prev := p.env.events
+	p.env.events = p.env.stderr
+	return func() {
+		p.env.events = prev
+	}
+}
+
+// Emit writes event to the event stream as a line of JSON, when events are on
+func (dc *defaultContext) Emit(event interface{}) {
+	if dc.events == nil {
+		return
+	}
+	writeEvent(dc.events, event)
+}
+
+// writeEvent writes event to w as a line of JSON. Events are best effort, failing to write one
+// doesn't fail the command.
+func writeEvent(w io.Writer, event interface{}) {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	w.Write(append(b, '\n'))
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// WithProjectConfig loads default flags from the nearest file called filename, such as ".greetrc",
+// looking in the working directory and then each of its parents. The file holds flags split as
+// SplitArgs does, over as many lines as needed, with lines starting with # ignored. They are given
+// to the command ahead of the command line, so flags given on the command line override them.
+// Each command is only given the flags it defines, so one file can hold flags for several
+// commands, and the program's builtin commands are given none. The file can't hold positional
+// args.
+func WithProjectConfig(filename string) Option {
+	return func(p *Program) {
+		p.projectConfig = filename
+	}
+}
+
+// projectArgs returns the flags in the nearest project config file, if there is one, that cmd
+// defines in fs
+func (p *Program) projectArgs(fs *flag.FlagSet, cmd Command) ([]string, error) {
+	if p.projectConfig == "" || isBuiltin(cmd) {
+		return nil, nil
+	}
+
+	path, ok := findUp(p.env.WorkingDir, p.projectConfig)
+	if !ok {
+		return nil, nil
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []string
+	for _, line := range strings.Split(string(b), "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "#") {
+			lines = append(lines, line)
+		}
+	}
+
+	args, err := SplitArgs(strings.Join(lines, "\n"))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	args, err = definedFlags(fs, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return args, nil
+}
+
+// definedFlags returns the flags in args that fs defines, along with their values. A flag fs
+// doesn't define takes the arg after it as its value unless that is a flag too, as its type isn't
+// known. Any other arg that isn't a flag is an error.
+func definedFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var defined []string
+	for i := 0; i < len(args); i++ {
+		name, _, hasValue := splitFlag(args[i])
+		if name == "" {
+			return nil, fmt.Errorf("%q is not a flag, only flags can be given", args[i])
+		}
+
+		f := fs.Lookup(name)
+		takesNext := !hasValue && i+1 < len(args)
+		if f != nil {
+			takesNext = takesNext && !isBoolFlag(f)
+		} else if takesNext {
+			next, _, _ := splitFlag(args[i+1])
+			takesNext = next == ""
+		}
+
+		end := i + 1
+		if takesNext {
+			end++
+		}
+		if f != nil {
+			defined = append(defined, args[i:end]...)
+		}
+		i = end - 1
+	}
+	return defined, nil
+}
+
+// findUp looks for a file called name in dir and then each of its parents, returning the path of
+// the nearest
+func findUp(dir, name string) (string, bool) {
+	for {
+		path := filepath.Join(dir, name)
+		if fi, err := os.Stat(path); err == nil && !fi.IsDir() {
+			return path, true
+		} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return "", false
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Retrier is implemented by idempotent commands that should be run again when they fail with a
+// retryable error, up to MaxRetries more times.
+type Retrier interface {
+	MaxRetries() int
+}
+
+// defaultRetryBackoff is the wait before the first retry of a Retrier, doubling for each retry
+const defaultRetryBackoff = time.Second
+
+// maxRetries returns how many times cmd may be retried
+func maxRetries(cmd Command) int {
+	if r, ok := cmd.(Retrier); ok {
+		return r.MaxRetries()
+	}
+	return 0
+}
+
+// retrying wraps fn to retry the command up to n times
+func retrying(n int, fn RunFunc) RunFunc {
+	return func(env *Environment, cmd Command, args []string) error {
+		return retry(env.context(), n, defaultRetryBackoff, env.warn, func() error {
+			return fn(env, cmd, args)
+		})
+	}
+}
+
+// WithRetry wraps fn so that it is called again when it returns an error with a Retryable() bool
+// method reporting true, up to n more times. It waits backoff before the first retry, doubling the
+// wait each time, and gives up early when the context is done. Other errors are returned straight
+// away. Each retry is warned about on the context's ErrLog.
+func WithRetry(n int, backoff time.Duration, fn func(Context) error) func(Context) error {
+	return func(ctx Context) error {
+		warn := func(format string, v ...interface{}) {
+			ctx.ErrLog().Printf("warning: "+format, v...)
+		}
+		return retry(ctx, n, backoff, warn, func() error {
+			return fn(ctx)
+		})
+	}
+}
+
+// retry calls run until it succeeds, fails with an error that isn't retryable, or has been retried
+// n times, reporting each retry with warn
+func retry(ctx context.Context, n int, backoff time.Duration, warn func(string, ...interface{}), run func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := run()
+		if err == nil || attempt > n || !isRetryable(err) {
+			return err
+		}
+
+		warn("attempt %d of %d failed, retrying in %s: %v", attempt, n+1, backoff, err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// isRetryable checks whether err reports itself as worth retrying
+func isRetryable(err error) bool {
+	var r interface{ Retryable() bool }
+	return errors.As(err, &r) && r.Retryable()
+}
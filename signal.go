@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// WithSignalCancel cancels the Context passed to commands when the program is sent SIGINT or
+// SIGTERM, such as by Ctrl-C, so long running commands can select on ctx.Done() and shut down
+// cleanly. A second signal kills the program as usual.
+func WithSignalCancel() Option {
+	return func(p *Program) {
+		p.signalCancel = true
+	}
+}
+
+// withSignals cancels the environment's context on SIGINT or SIGTERM until the returned func is
+// called, which stops catching them
+func (e *Environment) withSignals() func() {
+	parent := e.context()
+	ctx, stop := signal.NotifyContext(parent, os.Interrupt, syscall.SIGTERM)
+	e.ctx = ctx
+	return func() {
+		stop()
+		e.ctx = parent
+	}
+}
+
+// GetContext returns a Context for the environment like GetDefaultContext, but built on ctx so
+// commands are cancelled along with it. The program's own deadline and values, such as the output
+// format, aren't carried over unless ctx was derived from the default context.
+func (e *Environment) GetContext(ctx context.Context) Context {
+	dc := e.GetDefaultContext().(*defaultContext)
+	dc.Context = ctx
+	return dc
+}
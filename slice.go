@@ -0,0 +1,33 @@
+package cmd
+
+import "strings"
+
+// StringSlice is a flag.Value collecting the values of a flag given several times, either
+// repeated or comma separated:
+//
+//	var headers cmd.StringSlice
+//	fs.Var(&headers, "header", "Add a header")
+//	fs.Parse([]string{"-header", "a", "-header", "b,c"}) // headers is [a b c]
+//
+// Values are appended to what the slice already holds, so any default is kept. The usage shows
+// the default comma separated.
+type StringSlice []string
+
+func (s *StringSlice) Get() interface{} { return []string(*s) }
+func (s *StringSlice) String() string   { return strings.Join(*s, ",") }
+
+// Set appends each of the comma separated values in v, leaving out empty ones
+func (s *StringSlice) Set(v string) error {
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*s = append(*s, item)
+		}
+	}
+	return nil
+}
+
+// reset drops the values given and sets the slice back to def, which Set can't as it appends
+func (s *StringSlice) reset(def string) error {
+	*s = nil
+	return s.Set(def)
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrUnterminatedQuote is returned by SplitArgs when a quoted segment is never closed.
+var ErrUnterminatedQuote = errors.New("unterminated quote")
+
+// ErrTrailingEscape is returned by SplitArgs when a line ends with an unescaped backslash.
+var ErrTrailingEscape = errors.New("trailing backslash")
+
+// SplitArgs splits a line into arguments the way a POSIX shell would, without any expansion.
+// Arguments are separated by unquoted whitespace. Within single quotes everything is literal,
+// within double quotes a backslash escapes only ", \, $ and `, and elsewhere a backslash escapes
+// the next character. Quoted and unquoted segments next to each other join into one argument, so
+// a'b c'"d" is the single argument "ab cd".
+func SplitArgs(line string) ([]string, error) {
+	var (
+		args    []string
+		arg     strings.Builder
+		inArg   bool // an argument has been started, possibly by an empty pair of quotes
+		quote   rune // the quote currently open, if any
+		escaped bool
+	)
+
+	for _, r := range line {
+		switch {
+		case escaped:
+			if quote == '"' && !strings.ContainsRune("\"\\$`", r) {
+				arg.WriteRune('\\')
+			}
+			arg.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				arg.WriteRune(r)
+			}
+		case r == '\\':
+			escaped = true
+			inArg = true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				arg.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	switch {
+	case escaped:
+		return nil, ErrTrailingEscape
+	case quote != 0:
+		return nil, ErrUnterminatedQuote
+	case inArg:
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
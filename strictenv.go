@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// EnvDeclarer is implemented by commands that read environment variables of their own, so strict
+// environment checking knows to expect them.
+type EnvDeclarer interface {
+	EnvVars() []string
+}
+
+// WithStrictEnv checks the environment for variables with the program's prefix that nothing reads,
+// such as a mistyped MYTOOL_VERBOSEE, and warns about each one. The prefix is the one given to
+// BindEnv, or the upper cased program name. Recognized variables are those bound to flags, those
+// declared by commands implementing EnvDeclarer and those the program reads itself. Giving the
+// -strict-env flag turns the warnings into an error.
+func WithStrictEnv() Option {
+	return func(p *Program) {
+		p.strictEnv = true
+		p.flags.BoolVar(&p.strictEnvFatal, "strict-env", false, "Fail on unrecognized environment variables")
+	}
+}
+
+// checkEnv looks for unrecognized environment variables when strict environment checking is on
+func (p *Program) checkEnv() error {
+	if !p.strictEnv {
+		return nil
+	}
+
+	prefix := p.envBinding
+	if prefix == "" {
+		prefix = p.envPrefix()
+	}
+
+	known := p.knownEnv()
+	var unknown []string
+	for _, kv := range p.env.Env {
+		k, _, _ := strings.Cut(kv, "=")
+		if strings.HasPrefix(k, prefix) && !known[k] {
+			unknown = append(unknown, k)
+		}
+	}
+	sort.Strings(unknown)
+
+	if len(unknown) > 0 && p.strictEnvFatal {
+		return fmt.Errorf("unrecognized environment variables: %s", strings.Join(unknown, ", "))
+	}
+	for _, k := range unknown {
+		p.env.warn("unrecognized environment variable %s", k)
+	}
+	return nil
+}
+
+// knownEnv returns the set of environment variables the program reads, down through command
+// groups
+func (p *Program) knownEnv() map[string]bool {
+	known := map[string]bool{
+		p.envPrefix() + "SNAPSHOT": true,
+	}
+
+	addFlags := func(fs *flag.FlagSet) {
+		if p.envBinding == "" {
+			return
+		}
+		fs.VisitAll(func(f *flag.Flag) {
+			known[p.envKey(f.Name)] = true
+		})
+	}
+	addFlags(p.flags)
+
+	cmds := p.allCommands()
+	if p.root != nil {
+		cmds = append([]Command{p.root}, cmds...)
+	}
+	var walk func(cmds []Command)
+	walk = func(cmds []Command) {
+		for _, cmd := range cmds {
+			addFlags(p.scratchFlagSet(cmd))
+			if d, ok := cmd.(EnvDeclarer); ok {
+				for _, k := range d.EnvVars() {
+					known[k] = true
+				}
+			}
+			walk(subcommands(cmd))
+		}
+	}
+	walk(cmds)
+	return known
+}
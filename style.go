@@ -0,0 +1,40 @@
+package cmd
+
+// UsageStyle controls the look of the usage without replacing it wholesale.
+type UsageStyle struct {
+	Indent    string              // written before each command and flag, "  " by default
+	Heading   func(string) string // renders a heading such as "Commands" or "Flags", adding a ":" by default
+	Separator string              // written between names and their descriptions, "  " by default
+}
+
+// defaultUsageStyle is the style of the usage unless changed with WithUsageStyle
+var defaultUsageStyle = UsageStyle{
+	Indent:    "  ",
+	Heading:   func(s string) string { return s + ":" },
+	Separator: "  ",
+}
+
+// WithUsageStyle changes the indentation, headings and separators of the usage, e.g. a 4 space
+// indent with upper case headings:
+//
+//	cmd.WithUsageStyle(cmd.UsageStyle{Indent: "    ", Heading: strings.ToUpper})
+//
+// Fields left empty keep their default.
+func WithUsageStyle(style UsageStyle) Option {
+	return func(p *Program) {
+		if style.Indent != "" {
+			p.style.Indent = style.Indent
+		}
+		if style.Heading != nil {
+			p.style.Heading = style.Heading
+		}
+		if style.Separator != "" {
+			p.style.Separator = style.Separator
+		}
+	}
+}
+
+// usageTable returns a table for the commands or flags of the usage
+func (p *Program) usageTable() *table {
+	return &table{sep: p.style.Separator, width: p.wrapWidth()}
+}
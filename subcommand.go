@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxRunDepth is how deeply commands can run each other with Context.Run
+const maxRunDepth = 16
+
+// withSubcommands lets the running command run other commands through its context, with the same
+// fn, until the returned func is called
+func (p *Program) withSubcommands(fn RunFunc) func() {
+	prev := p.env.runSub
+	p.env.runSub = func(name string, args []string) error {
+		return p.runSubcommand(name, args, fn)
+	}
+	return func() {
+		p.env.runSub = prev
+	}
+}
+
+// runSubcommand runs the command called name with args, parsing them with a fresh FlagSet. A group
+// is descended into as on the command line, and is an error without a command of its own to run.
+func (p *Program) runSubcommand(name string, args []string, fn RunFunc) error {
+	if p.env.depth >= maxRunDepth {
+		return fmt.Errorf("%s: %s: commands run more than %d deep", p.name, name, maxRunDepth)
+	}
+
+	cmd, args, err := p.lookupPath(name, args)
+	if err != nil {
+		return err
+	}
+	if isGroupOnly(cmd) {
+		return fmt.Errorf("%s: no command given", p.commandPath(cmd))
+	}
+
+	p.env.depth++
+	defer func() { p.env.depth-- }()
+	return p.runCommand(cmd, args, noHelp, fn)
+}
+
+// Execute runs the command called name, or the default command when name is "", with args as they
+// would follow its name on the command line. Unlike Run it doesn't parse the program's flags, so it
+// suits an interactive shell built on the program's commands. The command is run through the
+// RunFunc given to the last call to Run, or straight away with its default context if Run hasn't
+// been called. Program wide flags from earlier runs are put back to their defaults. Commands in
+// groups are run with the group's name followed by theirs, either in name as "remote add" or as the
+// first of args.
+func (p *Program) Execute(name string, args []string) error {
+	p.resetGlobalFlags()
+	defer p.keepWorkingDir()()
+
+	cmd, args, err := p.lookupPath(name, args)
+	if err != nil {
+		return err
+	}
+
+	fn := p.runFunc
+	if fn == nil {
+		fn = func(env *Environment, cmd Command, args []string) error {
+			return cmd.Run(env.GetDefaultContext(), args)
+		}
+	}
+	return p.runCommand(cmd, args, noHelp, fn)
+}
+
+// lookupPath finds the command called name, following args down through groups as the command
+// line does, and returns it with the args left for it. A name such as "remote add" is split into
+// the group and the command in it.
+func (p *Program) lookupPath(name string, args []string) (Command, []string, error) {
+	if fields := strings.Fields(name); len(fields) > 1 {
+		name, args = fields[0], append(fields[1:], args...)
+	}
+
+	cmd, err := p.lookupCommand(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return p.descend(cmd, args)
+}
+
+// Run runs the command called name with args as if it had been given on the command line, sharing
+// the running command's context
+func (dc *defaultContext) Run(name string, args []string) error {
+	if dc.runSub == nil {
+		return fmt.Errorf("cannot run %s, no command is running", name)
+	}
+	return dc.runSub(name, args)
+}
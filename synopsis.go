@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"flag"
+	"strings"
+)
+
+// Synopsis returns a one line summary of how to call a command, e.g. "greet [-p|-pirate] [name]",
+// with each flag in brackets followed by the command's args.
+func (p *Program) Synopsis(cmdName string) (string, error) {
+	cmd, err := p.lookupCommand(cmdName)
+	if err != nil {
+		return "", err
+	}
+	return p.synopsis(p.scratchFlagSet(cmd), cmd), nil
+}
+
+// commandPath returns how cmd is called, e.g. "prog deploy" or "prog remote add" for a command in
+// a group, or just "prog" for the default command
+func (p *Program) commandPath(cmd Command) string {
+	if p.root != nil && p.root.Name() == cmd.Name() {
+		return p.name
+	}
+	if path := groupPath(p.allCommands(), cmd); path != nil {
+		return p.name + " " + strings.Join(path, " ")
+	}
+	return p.name + " " + cmd.Name()
+}
+
+// synopsis builds the synopsis of cmd from its flags in fs
+func (p *Program) synopsis(fs *flag.FlagSet, cmd Command) string {
+	parts := []string{p.commandPath(cmd)}
+
+	groups := groupFlags(fs, false, func(f *flag.Flag) bool {
+		return !p.isGlobalFlag(f)
+	})
+	for _, group := range groups {
+		names := make([]string, len(group))
+		for i, f := range group {
+			names[i] = "-" + f.Name
+		}
+
+		f := group[len(group)-1]
+		s := strings.Join(names, "|")
+		if !isBoolFlag(f) {
+			name, _ := unquoteUsage(f)
+			if name == "" {
+				name = "value"
+			}
+			s += " " + name
+		}
+		parts = append(parts, "["+s+"]")
+	}
+
+	if args := strings.TrimSpace(cmd.Args()); args != "" {
+		parts = append(parts, args)
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"io"
+	"strings"
+	"unicode"
+)
+
+// table aligns rows of cells into columns. Unlike text/tabwriter, which assumes every rune is
+// one column wide, cells are measured by their display width so East Asian wide characters and
+// combining marks don't throw out the alignment.
+type table struct {
+	sep   string // written between columns
+	width int    // the width to wrap the last column to, 0 for no wrapping
+	rows  [][]string
+}
+
+// newTable returns a table separating columns by at least padding spaces
+func newTable(padding int) *table {
+	return &table{sep: strings.Repeat(" ", padding)}
+}
+
+// row adds a row to the table
+func (t *table) row(cells ...string) {
+	t.rows = append(t.rows, cells)
+}
+
+// write renders the table to w a row at a time, stopping at the first failed write. Every column
+// but the last is padded to the width of its widest cell and followed by the separator, the last is
+// written as is or, with a width set, wrapped to it and lined up under itself.
+func (t *table) write(w io.Writer) error {
+	var widths []int
+	for _, r := range t.rows {
+		for i, c := range r[:len(r)-1] {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			if cw := stringWidth(c); cw > widths[i] {
+				widths[i] = cw
+			}
+		}
+	}
+
+	var b strings.Builder
+	for _, r := range t.rows {
+		b.Reset()
+		for i, c := range r[:len(r)-1] {
+			b.WriteString(c)
+			b.WriteString(strings.Repeat(" ", widths[i]-stringWidth(c)))
+			b.WriteString(t.sep)
+		}
+		prefix := b.String()
+		b.Reset()
+		lines := wrapLine(r[len(r)-1], prefix, strings.Repeat(" ", stringWidth(prefix)), t.width)
+		b.WriteString(strings.Join(lines, "\n"))
+		b.WriteByte('\n')
+
+		if _, err := io.WriteString(w, b.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stringWidth returns the number of terminal columns needed to display s, ANSI escape sequences
+// such as colors take up none
+func stringWidth(s string) (n int) {
+	escape := false
+	for _, r := range s {
+		switch {
+		case r == 0x1b:
+			escape = true
+		case escape:
+			// a sequence ends at its final letter, e.g. the m of "\x1b[36m"
+			escape = !(r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z')
+		default:
+			n += runeWidth(r)
+		}
+	}
+	return n
+}
+
+// wideRanges are the code point ranges displayed as two columns, covering the East Asian Wide and
+// Fullwidth characters along with the emoji blocks
+var wideRanges = []struct{ lo, hi rune }{
+	{0x1100, 0x115f},
+	{0x231a, 0x231b},
+	{0x2329, 0x232a},
+	{0x23e9, 0x23ec},
+	{0x25fd, 0x25fe},
+	{0x2614, 0x2615},
+	{0x2648, 0x2653},
+	{0x26aa, 0x26ab},
+	{0x26bd, 0x26be},
+	{0x26c4, 0x26c5},
+	{0x2705, 0x2705},
+	{0x270a, 0x270b},
+	{0x2728, 0x2728},
+	{0x274c, 0x274c},
+	{0x2753, 0x2755},
+	{0x2795, 0x2797},
+	{0x2b1b, 0x2b1c},
+	{0x2e80, 0x303e},
+	{0x3041, 0x33ff},
+	{0x3400, 0x4dbf},
+	{0x4e00, 0x9fff},
+	{0xa000, 0xa4cf},
+	{0xa960, 0xa97f},
+	{0xac00, 0xd7a3},
+	{0xf900, 0xfaff},
+	{0xfe10, 0xfe19},
+	{0xfe30, 0xfe6f},
+	{0xff00, 0xff60},
+	{0xffe0, 0xffe6},
+	{0x16fe0, 0x18cff},
+	{0x1b000, 0x1b2ff},
+	{0x1f004, 0x1f004},
+	{0x1f0cf, 0x1f0cf},
+	{0x1f18e, 0x1f18e},
+	{0x1f191, 0x1f19a},
+	{0x1f200, 0x1f2ff},
+	{0x1f300, 0x1f64f},
+	{0x1f680, 0x1f6ff},
+	{0x1f7e0, 0x1f7eb},
+	{0x1f90c, 0x1f9ff},
+	{0x1fa70, 0x1faff},
+	{0x20000, 0x2fffd},
+	{0x30000, 0x3fffd},
+}
+
+// runeWidth returns the number of terminal columns needed to display r
+func runeWidth(r rune) int {
+	switch {
+	case r == 0x200b, unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		return 0
+	case r < 0x1100:
+		return 1
+	}
+	for _, wr := range wideRanges {
+		if r >= wr.lo && r <= wr.hi {
+			return 2
+		}
+	}
+	return 1
+}
+
+// stickyWriter writes to w until a write fails, after which it keeps returning that error without
+// writing, so a long render can carry on cheaply and check for an error once at the end
+type stickyWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (sw *stickyWriter) Write(b []byte) (int, error) {
+	if sw.err != nil {
+		return 0, sw.err
+	}
+
+	n, err := sw.w.Write(b)
+	sw.err = err
+	return n, err
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"strings"
+	"text/template"
+)
+
+// UsageData is what a usage template set with SetUsageTemplate is executed with.
+type UsageData struct {
+	Name     string         // the program name
+	Desc     string         // the program description
+	Root     *UsageCommand  // the default command, nil if there is none
+	Commands []UsageCommand // the commands shown in the usage, builtins included
+	Flags    []UsageFlag    // the program wide flags
+}
+
+// UsageCommand describes a command in UsageData.
+type UsageCommand struct {
+	Name    string
+	Aliases []string
+	Args    string
+	Desc    string
+}
+
+// UsageFlag describes a flag in UsageData.
+type UsageFlag struct {
+	Name    string
+	Usage   string
+	Default string
+}
+
+// SetUsageTemplate replaces the layout of the program usage with tmpl, executed with a UsageData:
+//
+//	tmpl := template.Must(template.New("usage").Parse(`{{.Name}} - {{.Desc}}
+//	{{range .Commands}}
+//	  {{.Name}}  {{.Desc}}{{end}}
+//	`))
+//	p.SetUsageTemplate(tmpl)
+//
+// The usage of each command keeps its own layout, and -porcelain output isn't affected. A nil tmpl
+// puts the built-in layout back.
+func (p *Program) SetUsageTemplate(tmpl *template.Template) {
+	p.usageTemplate = tmpl
+}
+
+// usageData gathers the data for the usage template, including hidden commands and flags when all
+// is set
+func (p *Program) usageData(all bool) *UsageData {
+	d := &UsageData{
+		Name: p.name,
+		Desc: strings.TrimSpace(p.desc),
+	}
+	if p.root != nil {
+		c := usageCommand(p.root)
+		d.Root = &c
+	}
+	for _, cmd := range visibleCommands(p.allCommands(), all) {
+		d.Commands = append(d.Commands, usageCommand(cmd))
+	}
+	for _, group := range groupFlags(p.flags, all, nil) {
+		for _, f := range group {
+			d.Flags = append(d.Flags, UsageFlag{Name: f.Name, Usage: f.Usage, Default: defaultString(f)})
+		}
+	}
+	return d
+}
+
+// usageCommand describes cmd for the usage template
+func usageCommand(cmd Command) UsageCommand {
+	return UsageCommand{
+		Name:    cmd.Name(),
+		Aliases: commandAliases(cmd),
+		Args:    cmd.Args(),
+		Desc:    cmd.Desc(),
+	}
+}
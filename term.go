@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"io"
+	"os"
+)
+
+// isTerminal reports whether v is a file attached to a terminal
+func isTerminal(v interface{}) bool {
+	f, ok := v.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// interactive reports whether stdout and stderr are both terminals, so the usage, which is written
+// to either, can be styled for one
+func (p *Program) interactive() bool {
+	return isTerminal(p.env.stdout) && isTerminal(p.env.stderr)
+}
+
+// StdinPiped reports whether the stdin of ctx has input piped or redirected into it, as with
+// "greet < names.txt", rather than being a terminal or the null device. Commands can use it to read
+// their input from stdin when no arguments are given.
+func StdinPiped(ctx Context) bool {
+	return isPiped(ctx.Stdin())
+}
+
+// isPiped reports whether r is a pipe, a regular file or a reader that isn't a file at all, such
+// as a buffer given by a test
+func isPiped(r io.Reader) bool {
+	f, ok := r.(*os.File)
+	if !ok {
+		return r != nil
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeNamedPipe != 0 || fi.Mode().IsRegular()
+}
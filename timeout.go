@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+)
+
+// WithTimeout gives every command d to run, after which the Context passed to Run is cancelled and
+// Run returns an error wrapping context.DeadlineExceeded. As with Timeouter, commands get a
+// -timeout flag to change it for a single run, and a Timeouter's DefaultTimeout takes precedence
+// over d. Commands that define their own -timeout flag are left to handle it themselves.
+func WithTimeout(d time.Duration) Option {
+	return func(p *Program) {
+		p.timeout = d
+	}
+}
+
+// Timeouter is implemented by commands that should be stopped after running for a while. Commands
+// implementing it get a -timeout flag defaulting to DefaultTimeout, the resulting deadline is
+// applied to the Context passed to Run. A timeout of zero means no timeout.
+type Timeouter interface {
+	DefaultTimeout() time.Duration
+}
+
+// defaultTimeout returns the default timeout for cmd and whether it has one, from Timeouter or the
+// program's WithTimeout
+func (p *Program) defaultTimeout(cmd Command) (time.Duration, bool) {
+	if t, ok := cmd.(Timeouter); ok {
+		return t.DefaultTimeout(), true
+	}
+	return p.timeout, p.timeout > 0
+}
+
+// timingOut wraps fn so the command is stopped after d, timed from when fn is called. A command
+// that ignores its context being done is returned from once the deadline passes and left to finish
+// in the background, so whatever it returns then is lost. It runs on its own copy of env, whose
+// output is cut off when it times out, so it neither races with the program restoring env nor
+// writes anywhere once Run has returned.
+func timingOut(d time.Duration, fn RunFunc) RunFunc {
+	return func(env *Environment, cmd Command, args []string) error {
+		ctx, cancel := context.WithTimeout(env.context(), d)
+		defer cancel()
+
+		g := &gate{}
+		run := env.detached(ctx, g)
+		done := make(chan error, 1)
+		go func() {
+			done <- fn(run, cmd, args)
+		}()
+
+		select {
+		case err := <-done:
+			return err
+		case <-ctx.Done():
+			if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return <-done
+			}
+			g.close()
+			return fmt.Errorf("%s: timed out after %s: %w", cmd.Name(), d, ctx.Err())
+		}
+	}
+}
+
+// detached returns a copy of the environment for a command running under ctx, writing its output
+// through g
+func (e *Environment) detached(ctx context.Context, g *gate) *Environment {
+	c := new(Environment)
+	*c = *e
+	c.Args = append([]string(nil), e.Args...)
+	c.Env = append([]string(nil), e.Env...)
+	c.ctx = ctx
+	c.stdout, c.stderr, c.events = g.writer(e.stdout), g.writer(e.stderr), g.writer(e.events)
+	c.outLog = log.New(envWriter{&c.stdout}, "", 0)
+	c.errLog = log.New(envWriter{&c.stderr}, "", 0)
+	return c
+}
+
+// gate passes writes through the writers it hands out until it is closed, after which they are
+// dropped. Writes hold the gate, so none is still in progress once close returns.
+type gate struct {
+	mu     sync.Mutex
+	closed bool
+}
+
+// writer returns w writing through the gate, nil for a nil w
+func (g *gate) writer(w io.Writer) io.Writer {
+	if w == nil {
+		return nil
+	}
+	return &gatedWriter{g: g, w: w}
+}
+
+func (g *gate) close() {
+	g.mu.Lock()
+	g.closed = true
+	g.mu.Unlock()
+}
+
+type gatedWriter struct {
+	g *gate
+	w io.Writer
+}
+
+func (gw *gatedWriter) Write(b []byte) (int, error) {
+	gw.g.mu.Lock()
+	defer gw.g.mu.Unlock()
+	if gw.g.closed {
+		return len(b), nil
+	}
+	return gw.w.Write(b)
+}
+
+// context returns the context for the running command
+func (e *Environment) context() context.Context {
+	if e.ctx == nil {
+		return context.Background()
+	}
+	return e.ctx
+}
@@ -0,0 +1,26 @@
+package cmd
+
+import "io"
+
+// SetOutputTransformer installs fn to wrap the stdout commands write to, e.g. to prefix every line
+// with a timestamp, so a program's output keeps a consistent style without each command applying
+// it. If the writer fn returns has a Flush() error method it is flushed once the command returns.
+func (p *Program) SetOutputTransformer(fn func(io.Writer) io.Writer) {
+	p.transform = fn
+}
+
+// transformStdout wraps stdout with fn until the returned func is called, which flushes the
+// wrapped writer when it supports it and puts the original stdout back
+func (e *Environment) transformStdout(fn func(io.Writer) io.Writer) func() error {
+	prev := e.stdout
+	w := fn(prev)
+	e.stdout = w
+
+	return func() error {
+		e.stdout = prev
+		if f, ok := w.(interface{ Flush() error }); ok {
+			return f.Flush()
+		}
+		return nil
+	}
+}
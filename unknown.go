@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"errors"
+	"strings"
+)
+
+// SetUnknownCommandHandler routes commands the program doesn't have to fn, given the name used and
+// the args after it, instead of Run returning ErrNoSuchCommand. It allows git style plugins, where
+// "prog foo" runs a separate prog-foo binary. Help for an unknown command is still an error, and a
+// program with a default command runs that instead as before.
+func (p *Program) SetUnknownCommandHandler(fn func(env *Environment, name string, args []string) error) {
+	p.unknownCommand = fn
+}
+
+// isUnknownCommand checks whether err is for the command named by args[1] not existing, so it
+// should go to the unknown command handler. Unknown flags aren't taken for commands.
+func (p *Program) isUnknownCommand(err error, args []string) bool {
+	var nsc *ErrNoSuchCommand
+	return p.unknownCommand != nil && len(args) > 1 && !strings.HasPrefix(args[1], "-") && errors.As(err, &nsc) && nsc.commandName == args[1]
+}
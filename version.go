@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+)
+
+// WithVersion sets the version of the program, e.g. "1.4.0", which may be stamped at build time
+// with -ldflags:
+//
+//	go build -ldflags "-X main.version=1.4.0"
+//
+// The version is printed by "prog version", "prog --version" and "prog -v". The flags give way to
+// flags of the same name the default command registers itself.
+func WithVersion(v string) Option {
+	return func(p *Program) {
+		p.version = v
+	}
+}
+
+// Version returns the version of the program, as set with WithVersion.
+func (p *Program) Version() string {
+	return p.version
+}
+
+// isVersionFlag checks whether arg asks for the program version
+func (p *Program) isVersionFlag(arg string) bool {
+	if p.version == "" {
+		return false
+	}
+
+	name, _, hasValue := splitFlag(arg)
+	if hasValue || (name != "version" && name != "v") {
+		return false
+	}
+	if p.root != nil {
+		fs := flag.NewFlagSet(p.root.Name(), flag.ContinueOnError)
+		p.root.Register(fs)
+		return fs.Lookup(name) == nil
+	}
+	return true
+}
+
+// versionCommand prints the program version
+type versionCommand struct {
+	p *Program
+}
+
+func (c *versionCommand) Name() string              { return "version" }
+func (c *versionCommand) Args() string              { return "" }
+func (c *versionCommand) Desc() string              { return "Print the version" }
+func (c *versionCommand) Help() string              { return "Print the version of " + c.p.name + "." }
+func (c *versionCommand) Register(fs *flag.FlagSet) {}
+
+func (c *versionCommand) Run(ctx Context, args []string) error {
+	_, err := fmt.Fprintf(ctx.Stdout(), "%s %s\n", c.p.name, c.p.version)
+	return err
+}
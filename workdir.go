@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// WithDirectoryFlag adds the -C flag, which runs the program as if it had been started in another
+// directory as with "git -C <dir>". Relative paths are taken from the working directory, each -C
+// from the one before it. The process doesn't change directory, the flag changes what
+// Environment.WorkingDir and Context.WorkingDir report for the run it is given to, so commands
+// should resolve paths against them, as OpenArg and CreateArg do.
+func WithDirectoryFlag() Option {
+	return func(p *Program) {
+		p.flags.Var(&dirValue{p: p}, "C", "Run as if started in the given directory")
+	}
+}
+
+// dirValue is the value of the -C flag, setting the working directory of the program's environment
+type dirValue struct {
+	p   *Program
+	dir string
+}
+
+func (v *dirValue) String() string { return v.dir }
+
+// reset forgets the directory given, the working directory it was set in is put back by the run
+func (v *dirValue) reset(def string) error {
+	v.dir = def
+	return nil
+}
+
+func (v *dirValue) Set(s string) error {
+	dir := s
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(v.p.env.WorkingDir, dir)
+	}
+
+	fi, err := os.Stat(dir)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return fmt.Errorf("%s: no such directory", s)
+	case err != nil:
+		return err
+	case !fi.IsDir():
+		return fmt.Errorf("%s: not a directory", s)
+	}
+
+	v.dir = filepath.Clean(dir)
+	v.p.env.WorkingDir = v.dir
+	return nil
+}
+
+// keepWorkingDir returns a func putting the working directory of the program's environment back to
+// what it is now, undoing any -C given to a run
+func (p *Program) keepWorkingDir() func() {
+	wd := p.env.WorkingDir
+	return func() {
+		p.env.WorkingDir = wd
+	}
+}
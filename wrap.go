@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"strconv"
+	"strings"
+)
+
+// defaultUsageWidth is the width the usage is wrapped to on a terminal when COLUMNS isn't set
+const defaultUsageWidth = 80
+
+// WithUsageWidth wraps descriptions and help in the usage at width columns, wherever the usage is
+// written. By default the usage is wrapped to the width of the terminal, from the COLUMNS
+// environment variable or 80 when it isn't set, and not at all when stdout or stderr isn't a
+// terminal or in snapshot mode, so piped output stays stable. A width of 0 turns wrapping off.
+func WithUsageWidth(width int) Option {
+	return func(p *Program) {
+		p.usageWidth = width
+		if width <= 0 {
+			p.usageWidth = -1
+		}
+	}
+}
+
+// wrapWidth returns the width to wrap the usage at, 0 for no wrapping
+func (p *Program) wrapWidth() int {
+	switch {
+	case p.usageWidth > 0:
+		return p.usageWidth
+	case p.usageWidth < 0, p.snapshotMode(), !p.interactive():
+		return 0
+	}
+	if n, err := strconv.Atoi(p.env.getenv("COLUMNS")); err == nil && n > 0 {
+		return n
+	}
+	return defaultUsageWidth
+}
+
+// wrapText wraps the lines of s that are wider than width between words, continuing each with its
+// own indentation. Lines that fit are left as they are, keeping any layout the text has.
+func wrapText(s string, width int) string {
+	if width <= 0 {
+		return s
+	}
+
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		text := strings.TrimLeft(line, " \t")
+		indent := line[:len(line)-len(text)]
+		lines = append(lines, wrapLine(text, indent, indent, width)...)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// wrapLine breaks s into lines no wider than width where it can, the first starting with first and
+// the rest with indent. Words wider than the width are left whole.
+func wrapLine(s, first, indent string, width int) []string {
+	if width <= 0 || stringWidth(first+s) <= width {
+		return []string{first + s}
+	}
+
+	var lines []string
+	line, empty := first, true
+	for _, word := range strings.Fields(s) {
+		if !empty && stringWidth(line)+1+stringWidth(word) > width {
+			lines = append(lines, line)
+			line, empty = indent, true
+		}
+		if !empty {
+			line += " "
+		}
+		line += word
+		empty = false
+	}
+	return append(lines, line)
+}
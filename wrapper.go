@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ShellEvalPrefix marks a line of output as a shell command for the wrapper generated by
+// GenShellWrapper to evaluate in the calling shell.
+const ShellEvalPrefix = "__cmd_eval__ "
+
+// ShellEval asks the calling shell to run command, it must be on a single line. It only has an
+// effect when the program is run through the function generated by GenShellWrapper.
+func ShellEval(ctx Context, command string) error {
+	_, err := fmt.Fprintf(ctx.Stdout(), "%s%s\n", ShellEvalPrefix, command)
+	return err
+}
+
+// GenShellWrapper writes a shell function of the same name as the program to w, for changing the
+// state of the calling shell in ways a child process can't, such as its working directory.
+// Supported shells are bash, zsh and fish.
+//
+// The function runs the program and goes through its stdout once it exits. Lines starting with
+// ShellEvalPrefix, as written by ShellEval, are evaluated by the shell and everything else is
+// printed as normal. Since stdout is captured it only appears after the program has exited, stderr
+// is passed straight through. The function returns the program's exit status.
+func (p *Program) GenShellWrapper(shell string, w io.Writer) error {
+	var b strings.Builder
+
+	switch shell {
+	case "bash", "zsh":
+		fmt.Fprintf(&b, "# %s shell wrapper for %s\n", shell, p.name)
+		fmt.Fprintf(&b, "%s() {\n", p.name)
+		b.WriteString("\tlocal out line code\n")
+		fmt.Fprintf(&b, "\tout=\"$(command %s \"$@\")\"\n", p.name)
+		b.WriteString("\tcode=$?\n")
+		b.WriteString("\tif [ -n \"$out\" ]; then\n")
+		b.WriteString("\t\twhile IFS= read -r line; do\n")
+		b.WriteString("\t\t\tcase \"$line\" in\n")
+		fmt.Fprintf(&b, "\t\t\t%s*) eval \"${line#%s}\" ;;\n", shellQuote(ShellEvalPrefix), shellQuote(ShellEvalPrefix))
+		b.WriteString("\t\t\t*) printf '%s\\n' \"$line\" ;;\n")
+		b.WriteString("\t\t\tesac\n")
+		b.WriteString("\t\tdone <<< \"$out\"\n")
+		b.WriteString("\tfi\n")
+		b.WriteString("\treturn $code\n")
+		b.WriteString("}\n")
+	case "fish":
+		fmt.Fprintf(&b, "# fish shell wrapper for %s\n", p.name)
+		fmt.Fprintf(&b, "function %s\n", p.name)
+		fmt.Fprintf(&b, "\tset -l out (command %s $argv)\n", p.name)
+		b.WriteString("\tset -l code $status\n")
+		b.WriteString("\tfor line in $out\n")
+		fmt.Fprintf(&b, "\t\tif string match -q -- %s $line\n", fishQuote(ShellEvalPrefix+"*"))
+		fmt.Fprintf(&b, "\t\t\teval (string replace -- %s '' $line)\n", fishQuote(ShellEvalPrefix))
+		b.WriteString("\t\telse\n")
+		b.WriteString("\t\t\tprintf '%s\\n' $line\n")
+		b.WriteString("\t\tend\n")
+		b.WriteString("\tend\n")
+		b.WriteString("\treturn $code\n")
+		b.WriteString("end\n")
+	default:
+		return fmt.Errorf("shell wrapper: unsupported shell %q", shell)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}